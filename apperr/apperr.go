@@ -0,0 +1,157 @@
+// Package apperr defines the typed errors handlers return and the Fiber
+// error handler that renders them as RFC 7807 problem details.
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Code is a stable, machine readable error identifier.
+type Code string
+
+const (
+	CodeBadRequest           Code = "bad_request"
+	CodeUnauthorized         Code = "unauthorized"
+	CodeForbidden            Code = "forbidden"
+	CodeNotFound             Code = "not_found"
+	CodeConflict             Code = "conflict"
+	CodePreconditionFailed   Code = "precondition_failed"
+	CodePreconditionRequired Code = "precondition_required"
+	CodeValidationFailed     Code = "validation_failed"
+	CodeUnsupportedMediaType Code = "unsupported_media_type"
+	CodeRateLimited          Code = "rate_limited"
+	CodeUnavailable          Code = "unavailable"
+	CodeInternal             Code = "internal"
+)
+
+// Error is an error with an HTTP status and a client-safe detail message.
+// The wrapped Err is logged but never sent to the client.
+type Error struct {
+	Code   Code
+	Status int
+	Detail string
+	Fields map[string]string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Detail, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func BadRequest(detail string) *Error {
+	return &Error{Code: CodeBadRequest, Status: http.StatusBadRequest, Detail: detail}
+}
+
+func Unauthorized(detail string) *Error {
+	return &Error{Code: CodeUnauthorized, Status: http.StatusUnauthorized, Detail: detail}
+}
+
+func Forbidden(detail string) *Error {
+	return &Error{Code: CodeForbidden, Status: http.StatusForbidden, Detail: detail}
+}
+
+func NotFound(detail string) *Error {
+	return &Error{Code: CodeNotFound, Status: http.StatusNotFound, Detail: detail}
+}
+
+func Conflict(detail string) *Error {
+	return &Error{Code: CodeConflict, Status: http.StatusConflict, Detail: detail}
+}
+
+func PreconditionFailed(detail string) *Error {
+	return &Error{Code: CodePreconditionFailed, Status: http.StatusPreconditionFailed, Detail: detail}
+}
+
+func PreconditionRequired(detail string) *Error {
+	return &Error{Code: CodePreconditionRequired, Status: http.StatusPreconditionRequired, Detail: detail}
+}
+
+// Validation reports per-field validation failures.
+func Validation(fields map[string]string) *Error {
+	return &Error{
+		Code:   CodeValidationFailed,
+		Status: http.StatusUnprocessableEntity,
+		Detail: "One or more fields are invalid",
+		Fields: fields,
+	}
+}
+
+func UnsupportedMediaType(detail string) *Error {
+	return &Error{Code: CodeUnsupportedMediaType, Status: http.StatusUnsupportedMediaType, Detail: detail}
+}
+
+func TooManyRequests(detail string) *Error {
+	return &Error{Code: CodeRateLimited, Status: http.StatusTooManyRequests, Detail: detail}
+}
+
+func Unavailable(detail string, err error) *Error {
+	return &Error{Code: CodeUnavailable, Status: http.StatusServiceUnavailable, Detail: detail, Err: err}
+}
+
+// Internal wraps an unexpected error. detail is shown to the client, err
+// is only logged.
+func Internal(detail string, err error) *Error {
+	return &Error{Code: CodeInternal, Status: http.StatusInternalServerError, Detail: detail, Err: err}
+}
+
+// Postgres error codes the API maps to client errors.
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgNotNullViolation    = "23502"
+	pgCheckViolation      = "23514"
+	pgStringTooLong       = "22001"
+)
+
+// FromDB classifies a database error: missing rows become not_found with
+// the given detail, constraint violations become conflict or bad_request,
+// lost connections become unavailable and anything else is internal.
+func FromDB(err error, notFoundDetail string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return NotFound(notFoundDetail)
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return &Error{Code: CodeConflict, Status: http.StatusConflict, Detail: "A record with the same unique value already exists", Err: err}
+	}
+	if errors.Is(err, gorm.ErrForeignKeyViolated) {
+		return &Error{Code: CodeConflict, Status: http.StatusConflict, Detail: "The record is referenced by or references another record", Err: err}
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return Unavailable("The database did not respond in time", err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return &Error{Code: CodeConflict, Status: http.StatusConflict, Detail: "A record with the same unique value already exists", Err: err}
+		case pgForeignKeyViolation:
+			return &Error{Code: CodeConflict, Status: http.StatusConflict, Detail: "The record is referenced by or references another record", Err: err}
+		case pgNotNullViolation, pgCheckViolation, pgStringTooLong:
+			return &Error{Code: CodeBadRequest, Status: http.StatusBadRequest, Detail: "The record violates a database constraint", Err: err}
+		}
+		return Internal("Database error", err)
+	}
+
+	var connErr *pgconn.ConnectError
+	if errors.As(err, &connErr) || pgconn.SafeToRetry(err) {
+		return Unavailable("The database is unavailable", err)
+	}
+	return Internal("Database error", err)
+}
@@ -0,0 +1,108 @@
+package apperr
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MIMEProblemJSON is the media type of RFC 7807 problem details.
+const MIMEProblemJSON = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body, extended with the error
+// code, the request ID and per-field validation errors.
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Code      Code              `json:"code"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    map[string]string `json:"errors,omitempty"`
+}
+
+// Handler is the Fiber ErrorHandler. It renders *Error values as problem
+// details, maps Fiber's own errors (unknown route, bad method, oversized
+// body) to matching codes and hides everything else behind a generic
+// internal error. The underlying error is logged by the request logger,
+// never sent to the client.
+func Handler(c *fiber.Ctx, err error) error {
+	var appErr *Error
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &appErr):
+	case errors.As(err, &fiberErr):
+		appErr = fromFiber(fiberErr)
+	default:
+		appErr = Internal("An unexpected error occurred", err)
+	}
+
+	problem := Problem{
+		Type:      "urn:problem-type:wandhx:" + string(appErr.Code),
+		Title:     http.StatusText(appErr.Status),
+		Status:    appErr.Status,
+		Detail:    appErr.Detail,
+		Instance:  c.OriginalURL(),
+		Code:      appErr.Code,
+		RequestID: RequestID(c),
+		Errors:    appErr.Fields,
+	}
+	return c.Status(appErr.Status).JSON(problem, MIMEProblemJSON)
+}
+
+// renderedKey is the Locals key holding the error Render wrote.
+const renderedKey = "apperr:rendered"
+
+// Render writes err as the response with the app's error handler, as Fiber
+// would once err left the middleware chain, and keeps it for Rendered.
+// Middleware calls it when it must see the final status before returning.
+func Render(c *fiber.Ctx, err error) {
+	c.Locals(renderedKey, err)
+	if c.App().ErrorHandler(c, err) != nil {
+		_ = c.SendStatus(fiber.StatusInternalServerError)
+	}
+}
+
+// Rendered returns the error Render already wrote for c, or nil. Middleware
+// further out sees a nil error from c.Next() for such requests.
+func Rendered(c *fiber.Ctx) error {
+	err, _ := c.Locals(renderedKey).(error)
+	return err
+}
+
+// RequestID returns the ID the requestid middleware assigned to c.
+func RequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals("requestid").(string)
+	return id
+}
+
+func fromFiber(err *fiber.Error) *Error {
+	e := &Error{Status: err.Code, Detail: err.Message}
+	switch {
+	case err.Code == fiber.StatusNotFound:
+		e.Code = CodeNotFound
+	case err.Code == fiber.StatusUnauthorized:
+		e.Code = CodeUnauthorized
+	case err.Code == fiber.StatusForbidden:
+		e.Code = CodeForbidden
+	case err.Code == fiber.StatusConflict:
+		e.Code = CodeConflict
+	case err.Code == fiber.StatusPreconditionFailed:
+		e.Code = CodePreconditionFailed
+	case err.Code == fiber.StatusUnprocessableEntity:
+		e.Code = CodeValidationFailed
+	case err.Code == fiber.StatusUnsupportedMediaType:
+		e.Code = CodeUnsupportedMediaType
+	case err.Code == fiber.StatusTooManyRequests:
+		e.Code = CodeRateLimited
+	case err.Code == fiber.StatusServiceUnavailable:
+		e.Code = CodeUnavailable
+	case err.Code >= fiber.StatusInternalServerError:
+		e.Code = CodeInternal
+	default:
+		e.Code = CodeBadRequest
+	}
+	return e
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// apiKeyPrefix marks a bearer token as an API key rather than a JWT.
+const apiKeyPrefix = "wx_"
+
+// refreshTokenPrefix marks an opaque refresh token.
+const refreshTokenPrefix = "wxr_"
+
+// displayPrefixLen is how much of a key is kept in clear text so it can be
+// recognised in listings.
+const displayPrefixLen = 11
+
+// GenerateAPIKey mints a new random API key and returns the plaintext key,
+// its display prefix and the hash to store.
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	key, err = randomToken(apiKeyPrefix)
+	if err != nil {
+		return "", "", "", err
+	}
+	return key, key[:displayPrefixLen], HashToken(key), nil
+}
+
+// GenerateRefreshToken mints a new opaque refresh token and returns it with
+// the hash to store.
+func GenerateRefreshToken() (token, hash string, err error) {
+	token, err = randomToken(refreshTokenPrefix)
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashToken(token), nil
+}
+
+// HashAPIKey returns the hash under which an API key is stored.
+func HashAPIKey(key string) string {
+	return HashToken(key)
+}
+
+// HashToken returns the hex SHA-256 of a random token. Tokens carry 256
+// bits of randomness, so a fast unsalted hash is enough to make a leaked
+// table useless.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// IsAPIKey reports whether a bearer token has the API key format.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
@@ -0,0 +1,29 @@
+package auth
+
+import "errors"
+
+var (
+	// ErrMissingCredentials is returned when a request carries no credential.
+	ErrMissingCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials is returned for unknown, revoked or malformed
+	// credentials.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authentication methods a Principal can come from.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string
+	Method  string
+	Role    Role
+}
+
+// Can reports whether the principal's role grants perm.
+func (p *Principal) Can(perm Permission) bool {
+	return p != nil && p.Role.Allows(perm)
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+)
+
+// leeway tolerates small clock differences between token issuer and API.
+const leeway = 30 * time.Second
+
+// TokenConfig configures JWT signing and verification.
+type TokenConfig struct {
+	Algorithm  string
+	Secret     []byte          // HS256
+	PrivateKey *rsa.PrivateKey // RS256, only needed to sign
+	PublicKey  *rsa.PublicKey  // RS256
+	Issuer     string
+	// Audience is optional. When set, tokens are signed for it and must
+	// name it to verify.
+	Audience string
+	TTL      time.Duration
+}
+
+// Claims are the JWT claims understood by the API. Tokens without a role
+// are treated as viewers.
+type Claims struct {
+	jwt.RegisteredClaims
+	Role Role `json:"role,omitempty"`
+}
+
+// Tokens signs and verifies JWTs.
+type Tokens struct {
+	cfg TokenConfig
+}
+
+// NewTokens validates cfg and returns a Tokens for it.
+func NewTokens(cfg TokenConfig) (*Tokens, error) {
+	switch cfg.Algorithm {
+	case HS256:
+		if len(cfg.Secret) < 32 {
+			return nil, errors.New("HS256 secret must be at least 32 bytes")
+		}
+	case RS256:
+		if cfg.PublicKey == nil {
+			if cfg.PrivateKey == nil {
+				return nil, errors.New("RS256 requires a public or private key")
+			}
+			cfg.PublicKey = &cfg.PrivateKey.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+	if cfg.Issuer == "" {
+		return nil, errors.New("JWT issuer is required")
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 15 * time.Minute
+	}
+	return &Tokens{cfg: cfg}, nil
+}
+
+// Sign issues a token for subject with role that expires after the
+// configured TTL.
+func (t *Tokens) Sign(subject string, role Role) (string, error) {
+	now := time.Now()
+	claims := Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    t.cfg.Issuer,
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(t.cfg.TTL)),
+	}, Role: role}
+	if t.cfg.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{t.cfg.Audience}
+	}
+
+	switch t.cfg.Algorithm {
+	case HS256:
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.cfg.Secret)
+	default:
+		if t.cfg.PrivateKey == nil {
+			return "", errors.New("RS256 signing requires a private key")
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(t.cfg.PrivateKey)
+	}
+}
+
+// TTL is how long signed tokens stay valid.
+func (t *Tokens) TTL() time.Duration {
+	return t.cfg.TTL
+}
+
+// Verify checks the signature, algorithm, issuer, audience and expiry of
+// a token and returns its claims.
+func (t *Tokens) Verify(token string) (*Claims, error) {
+	claims := &Claims{}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{t.cfg.Algorithm}),
+		jwt.WithIssuer(t.cfg.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+	}
+	if t.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(t.cfg.Audience))
+	}
+	_, err := jwt.ParseWithClaims(token, claims, t.key, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+	if claims.Role == "" {
+		claims.Role = RoleViewer
+	}
+	if _, err := ParseRole(string(claims.Role)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	return claims, nil
+}
+
+func (t *Tokens) key(*jwt.Token) (interface{}, error) {
+	if t.cfg.Algorithm == HS256 {
+		return t.cfg.Secret, nil
+	}
+	return t.cfg.PublicKey, nil
+}
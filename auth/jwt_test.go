@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs, err := NewTokens(TokenConfig{Algorithm: HS256, Secret: testSecret, Issuer: "wandhx-be", Audience: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := NewTokens(TokenConfig{Algorithm: RS256, PublicKey: &rsaKey.PublicKey, Issuer: "wandhx-be"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: mustMarshalPKIX(t, &rsaKey.PublicKey)})
+
+	now := time.Now()
+	claims := func(modify func(*Claims)) Claims {
+		c := Claims{RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "wandhx-be",
+			Subject:   "user:1",
+			Audience:  jwt.ClaimStrings{"admin"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		}, Role: RoleEditor}
+		if modify != nil {
+			modify(&c)
+		}
+		return c
+	}
+	sign := func(method jwt.SigningMethod, key interface{}, c Claims) string {
+		token, err := jwt.NewWithClaims(method, c).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name   string
+		tokens *Tokens
+		token  string
+		ok     bool
+	}{
+		{"valid HS256", hs, sign(jwt.SigningMethodHS256, testSecret, claims(nil)), true},
+		{"valid RS256", rs, sign(jwt.SigningMethodRS256, rsaKey, claims(nil)), true},
+		{"expiry within leeway", hs, sign(jwt.SigningMethodHS256, testSecret, claims(func(c *Claims) {
+			c.ExpiresAt = jwt.NewNumericDate(now.Add(-leeway / 2))
+		})), true},
+		{"expired", hs, sign(jwt.SigningMethodHS256, testSecret, claims(func(c *Claims) {
+			c.ExpiresAt = jwt.NewNumericDate(now.Add(-2 * leeway))
+		})), false},
+		{"no expiry", hs, sign(jwt.SigningMethodHS256, testSecret, claims(func(c *Claims) { c.ExpiresAt = nil })), false},
+		{"not yet valid", hs, sign(jwt.SigningMethodHS256, testSecret, claims(func(c *Claims) {
+			c.NotBefore = jwt.NewNumericDate(now.Add(time.Hour))
+		})), false},
+		{"wrong issuer", hs, sign(jwt.SigningMethodHS256, testSecret, claims(func(c *Claims) { c.Issuer = "someone-else" })), false},
+		{"wrong audience", hs, sign(jwt.SigningMethodHS256, testSecret, claims(func(c *Claims) { c.Audience = jwt.ClaimStrings{"public"} })), false},
+		{"no audience", hs, sign(jwt.SigningMethodHS256, testSecret, claims(func(c *Claims) { c.Audience = nil })), false},
+		{"wrong secret", hs, sign(jwt.SigningMethodHS256, []byte("another-secret-of-at-least-32-bytes"), claims(nil)), false},
+		{"HS256 against RS256 signed with the public key", rs, sign(jwt.SigningMethodHS256, publicPEM, claims(nil)), false},
+		{"RS256 against HS256", hs, sign(jwt.SigningMethodRS256, rsaKey, claims(nil)), false},
+		{"alg none", hs, sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims(nil)), false},
+		{"no subject", hs, sign(jwt.SigningMethodHS256, testSecret, claims(func(c *Claims) { c.Subject = "" })), false},
+		{"unknown role", hs, sign(jwt.SigningMethodHS256, testSecret, claims(func(c *Claims) { c.Role = "admin" })), false},
+		{"malformed", hs, "not.a.jwt", false},
+		{"tampered payload", hs, tamper(sign(jwt.SigningMethodHS256, testSecret, claims(nil))), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.tokens.Verify(tt.token)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("Verify: %v", err)
+				}
+				if got.Subject != "user:1" || got.Role != RoleEditor {
+					t.Errorf("claims %+v", got)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("Verify = %+v, %v, want ErrInvalidCredentials", got, err)
+			}
+		})
+	}
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	tokens, err := NewTokens(TokenConfig{Algorithm: HS256, Secret: testSecret, Issuer: "wandhx-be", Audience: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := tokens.Sign("user:7", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := tokens.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user:7" || claims.Role != RoleViewer {
+		t.Errorf("claims %+v, want user:7 as a viewer", claims)
+	}
+}
+
+func TestNewTokensRejectsBadConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  TokenConfig
+	}{
+		{"short secret", TokenConfig{Algorithm: HS256, Secret: []byte("short"), Issuer: "i"}},
+		{"RS256 without keys", TokenConfig{Algorithm: RS256, Issuer: "i"}},
+		{"unsupported algorithm", TokenConfig{Algorithm: "none", Secret: testSecret, Issuer: "i"}},
+		{"no issuer", TokenConfig{Algorithm: HS256, Secret: testSecret}},
+	}
+	for _, tt := range tests {
+		if _, err := NewTokens(tt.cfg); err == nil {
+			t.Errorf("%s: NewTokens succeeded", tt.name)
+		}
+	}
+}
+
+// tamper changes the payload of a token without re-signing it.
+func tamper(token string) string {
+	parts := strings.Split(token, ".")
+	claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(claims), `"editor"`, `"owner"`, 1)))
+	return strings.Join(parts, ".")
+}
+
+func mustMarshalPKIX(t *testing.T, key *rsa.PublicKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
@@ -0,0 +1,47 @@
+package auth
+
+import "fmt"
+
+// Role is the access level of a principal.
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+// Permission is an action a route can require.
+type Permission string
+
+const (
+	PermContentRead   Permission = "content:read"
+	PermContentWrite  Permission = "content:write"
+	PermContentDelete Permission = "content:delete"
+	PermUsersManage   Permission = "users:manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleOwner:  {PermContentRead, PermContentWrite, PermContentDelete, PermUsersManage},
+	RoleEditor: {PermContentRead, PermContentWrite},
+	RoleViewer: {PermContentRead},
+}
+
+// ParseRole validates a role name.
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := rolePermissions[role]; !ok {
+		return "", fmt.Errorf("unknown role %q (want owner, editor or viewer)", s)
+	}
+	return role, nil
+}
+
+// Allows reports whether the role grants perm.
+func (r Role) Allows(perm Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
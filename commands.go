@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"wannn-site-rebuild-api/auth"
+	"wannn-site-rebuild-api/config"
+	"wannn-site-rebuild-api/logging"
+	"wannn-site-rebuild-api/migrations"
+	"wannn-site-rebuild-api/models"
+	"wannn-site-rebuild-api/repository"
+	"wannn-site-rebuild-api/server"
+)
+
+const usage = `Usage:
+  wandhx-be [flags]              start the API server (see -h for flags;
+                                 -config file.json loads a config file)
+  wandhx-be migrate up           apply all pending migrations
+  wandhx-be migrate down [n]     roll back the last n migrations (default 1)
+  wandhx-be migrate status       list migrations and whether they are applied
+  wandhx-be seed [-file path]    upsert seed content (embedded default or a JSON file)
+  wandhx-be apikey create [-role editor] <name>
+                                 mint an API key for the write routes
+  wandhx-be apikey list          list API keys
+  wandhx-be apikey revoke <id>   revoke an API key
+  wandhx-be create-admin -email <email>
+                                 create an owner account (password from
+                                 ADMIN_PASSWORD or stdin)`
+
+// setup loads the configuration through CONFIG_FILE and the environment
+// and installs its logger, as the server does. Logs go to stderr so that
+// stdout carries only the command's output.
+func setup() server.Config {
+	cfg, err := server.Load(nil)
+	if err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+	slog.SetDefault(logger)
+	return cfg
+}
+
+// exitUsage prints a usage message and exits with status 2, like the flag
+// package does.
+func exitUsage(text string) {
+	fmt.Fprintln(os.Stderr, text)
+	os.Exit(2)
+}
+
+// runCommand dispatches a CLI subcommand. It returns false when name is not
+// a known command.
+func runCommand(name string, args []string) bool {
+	switch name {
+	case "migrate":
+		runMigrate(args)
+	case "seed":
+		runSeed(args)
+	case "apikey":
+		runAPIKey(args)
+	case "create-admin":
+		runCreateAdmin(args)
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+	default:
+		return false
+	}
+	return true
+}
+
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		exitUsage(usage)
+	}
+
+	cfg := setup()
+	config.ConnectDatabase(cfg.Database)
+	if config.DB == nil {
+		logging.Fatal("The driver has no schema to migrate", "driver", cfg.Database.Driver)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrations.Up(config.DB)
+		if err != nil {
+			logging.Fatal("Failed to migrate database", "error", err)
+		}
+		if len(applied) == 0 {
+			slog.Info("No pending migrations")
+		}
+		for _, m := range applied {
+			slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				exitUsage(fmt.Sprintf("Invalid number of steps %q", args[1]))
+			}
+			steps = n
+		}
+		reverted, err := migrations.Down(config.DB, steps)
+		if err != nil {
+			logging.Fatal("Failed to roll back database", "error", err)
+		}
+		if len(reverted) == 0 {
+			slog.Info("No applied migrations to roll back")
+		}
+		for _, m := range reverted {
+			slog.Info("Rolled back migration", "version", m.Version, "name", m.Name)
+		}
+	case "status":
+		statuses, err := migrations.List(config.DB)
+		if err != nil {
+			logging.Fatal("Failed to read migration status", "error", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state, at := "pending", ""
+			if s.Applied {
+				state, at = "applied", s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, at)
+		}
+		w.Flush()
+	default:
+		exitUsage(usage)
+	}
+}
+
+func runSeed(args []string) {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	file := fs.String("file", os.Getenv("SEED_FILE"), "JSON seed file (defaults to the embedded content)")
+	fs.Parse(args)
+	cfg := setup()
+
+	data, err := config.LoadSeedData(*file)
+	if err != nil {
+		logging.Fatal("Failed to load seed data", "error", err)
+	}
+
+	config.ConnectDatabase(cfg.Database)
+	report, err := config.SeedDatabase(data)
+	if err != nil {
+		logging.Fatal("Failed to seed database", "error", err)
+	}
+	config.LogSeedReport(report)
+}
+
+func runAPIKey(args []string) {
+	if len(args) == 0 {
+		exitUsage(usage)
+	}
+
+	config.ConnectDatabase(setup().Database)
+	keys := config.Repos.APIKeys
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ExitOnError)
+		roleName := fs.String("role", string(auth.RoleEditor), "role granted to the key (owner, editor or viewer)")
+		fs.Parse(args[1:])
+		if fs.NArg() < 1 {
+			exitUsage("Usage: wandhx-be apikey create [-role editor] <name>")
+		}
+		role, err := auth.ParseRole(*roleName)
+		if err != nil {
+			exitUsage(err.Error())
+		}
+		key, prefix, hash, err := auth.GenerateAPIKey()
+		if err != nil {
+			logging.Fatal("Failed to generate API key", "error", err)
+		}
+		record := models.APIKey{Name: fs.Arg(0), Prefix: prefix, KeyHash: hash, Role: string(role)}
+		if err := keys.Create(ctx, &record); err != nil {
+			logging.Fatal("Failed to store API key", "error", err)
+		}
+		fmt.Printf("Created %s API key %d (%s). Store it now, it will not be shown again:\n%s\n", record.Role, record.ID, record.Name, key)
+	case "list":
+		list, err := keys.List(ctx)
+		if err != nil {
+			logging.Fatal("Failed to list API keys", "error", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPREFIX\tROLE\tLAST USED\tSTATUS")
+		for _, k := range list {
+			lastUsed, state := "never", "active"
+			if k.LastUsedAt != nil {
+				lastUsed = k.LastUsedAt.Format("2006-01-02 15:04:05")
+			}
+			if k.RevokedAt != nil {
+				state = "revoked"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Prefix, k.Role, lastUsed, state)
+		}
+		w.Flush()
+	case "revoke":
+		if len(args) < 2 {
+			exitUsage("Usage: wandhx-be apikey revoke <id>")
+		}
+		id, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			exitUsage(fmt.Sprintf("Invalid API key id %q", args[1]))
+		}
+		err = keys.Revoke(ctx, uint(id))
+		if errors.Is(err, repository.ErrNotFound) {
+			logging.Fatal("No active API key with this id", "id", id)
+		}
+		if err != nil {
+			logging.Fatal("Failed to revoke API key", "error", err)
+		}
+		slog.Info("Revoked API key", "id", id)
+	default:
+		exitUsage(usage)
+	}
+}
+
+func runCreateAdmin(args []string) {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	email := fs.String("email", "", "email address used to sign in")
+	fs.Parse(args)
+
+	if *email == "" {
+		exitUsage("Usage: wandhx-be create-admin -email <email>")
+	}
+	cfg := setup()
+
+	password := os.Getenv("ADMIN_PASSWORD")
+	if password == "" {
+		fmt.Print("Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			logging.Fatal("Failed to read password", "error", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		logging.Fatal("Invalid password", "error", err)
+	}
+
+	config.ConnectDatabase(cfg.Database)
+	users := config.Repos.Users
+	user := models.User{Email: strings.ToLower(strings.TrimSpace(*email)), PasswordHash: hash, Role: string(auth.RoleOwner)}
+	if err := users.Create(context.Background(), &user); err != nil {
+		logging.Fatal("Failed to create admin", "error", err)
+	}
+	slog.Info("Created admin", "id", user.ID, "email", user.Email)
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"wannn-site-rebuild-api/auth"
+	"wannn-site-rebuild-api/logging"
+)
+
+// LoadTokens builds the JWT signer/verifier from JWT_* environment
+// variables. It returns nil when neither JWT_SECRET nor an RSA key is
+// configured, in which case only API keys are accepted.
+func LoadTokens() *auth.Tokens {
+	cfg := auth.TokenConfig{
+		Algorithm: os.Getenv("JWT_ALGORITHM"),
+		Secret:    []byte(os.Getenv("JWT_SECRET")),
+		Issuer:    os.Getenv("JWT_ISSUER"),
+		Audience:  os.Getenv("JWT_AUDIENCE"),
+	}
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = auth.HS256
+	}
+	if cfg.Issuer == "" {
+		cfg.Issuer = "wandhx-be"
+	}
+	if ttl := os.Getenv("JWT_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			logging.Fatal("Invalid JWT_TTL", "value", ttl, "error", err)
+		}
+		cfg.TTL = d
+	}
+
+	if path := os.Getenv("JWT_PRIVATE_KEY_FILE"); path != "" {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			logging.Fatal("Failed to read JWT private key", "error", err)
+		}
+		if cfg.PrivateKey, err = jwt.ParseRSAPrivateKeyFromPEM(pem); err != nil {
+			logging.Fatal("Failed to parse JWT private key", "error", err)
+		}
+	}
+	if path := os.Getenv("JWT_PUBLIC_KEY_FILE"); path != "" {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			logging.Fatal("Failed to read JWT public key", "error", err)
+		}
+		if cfg.PublicKey, err = jwt.ParseRSAPublicKeyFromPEM(pem); err != nil {
+			logging.Fatal("Failed to parse JWT public key", "error", err)
+		}
+	}
+
+	if len(cfg.Secret) == 0 && cfg.PrivateKey == nil && cfg.PublicKey == nil {
+		slog.Warn("JWT is not configured, only API keys can authenticate")
+		return nil
+	}
+
+	tokens, err := auth.NewTokens(cfg)
+	if err != nil {
+		logging.Fatal("Invalid JWT configuration", "error", err)
+	}
+	return tokens
+}
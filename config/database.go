@@ -2,78 +2,176 @@ package config
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 
-	"github.com/joho/godotenv"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"wannn-site-rebuild-api/models"
+	"wannn-site-rebuild-api/logging"
+	"wannn-site-rebuild-api/migrations"
+	"wannn-site-rebuild-api/repository"
+	"wannn-site-rebuild-api/tracing"
 )
 
-var DB *gorm.DB
+// Storage drivers accepted by DB_DRIVER.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
 
-func InitDatabase() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Warning: Error loading .env file, will use system environment variables")
-	}
+// DatabaseConfig selects and configures the storage backend.
+type DatabaseConfig struct {
+	Driver string `json:"driver"` // postgres, sqlite or memory
 
-	// Get database configuration from environment variables
-	dbHost := os.Getenv("DB_HOST")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-	dbPort := os.Getenv("DB_PORT")
+	// Postgres connection settings
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	SSLMode  string `json:"sslmode"`
+
+	// SQLite database file; ":memory:" keeps it in memory
+	Path string `json:"path"`
+}
+
+// DB is the SQL connection pool. It is nil with the memory driver.
+var DB *gorm.DB
 
-	if dbHost == "" || dbUser == "" || dbPassword == "" || dbName == "" || dbPort == "" {
-		log.Fatal("Database configuration environment variables are required (DB_HOST, DB_USER, DB_PASSWORD, DB_NAME, DB_PORT)")
+// Repos are the repositories of the storage backend selected by DB_DRIVER.
+var Repos repository.Repositories
+
+// ConnectDatabase opens the storage backend described by cfg and stores it
+// in DB and Repos without touching the schema.
+func ConnectDatabase(cfg DatabaseConfig) {
+	switch cfg.Driver {
+	case DriverPostgres:
+		DB = connectPostgres(cfg)
+	case DriverSQLite:
+		db, err := OpenSQLite(cfg.Path)
+		if err != nil {
+			logging.Fatal("Failed to open SQLite database", "path", cfg.Path, "error", err)
+		}
+		DB = db
+	case DriverMemory:
+		Repos = repository.NewMemory()
+		slog.Warn("Using in-memory storage, nothing will be persisted")
+		return
+	default:
+		logging.Fatal("Unknown database driver, expected postgres, sqlite or memory", "driver", cfg.Driver)
 	}
 
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=require application_name=wandhx_be",
-		dbHost, dbUser, dbPassword, dbName, dbPort)
+	Repos = repository.NewGORM(DB)
+	slog.Info("Database connection established", "driver", cfg.Driver)
+}
+
+func connectPostgres(cfg DatabaseConfig) *gorm.DB {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s application_name=wandhx_be",
+		cfg.Host, cfg.User, cfg.Password, cfg.Name, cfg.Port, cfg.SSLMode)
 
 	// Configure GORM to handle prepared statements better
 	db, err := gorm.Open(postgres.New(postgres.Config{
-		DSN: dsn,
+		DSN:                  dsn,
 		PreferSimpleProtocol: true, // Disables implicit prepared statement usage
 	}), &gorm.Config{
 		PrepareStmt: false, // Disable prepared statement cache
+		// Report unique violations as gorm.ErrDuplicatedKey, like SQLite
+		TranslateError: true,
 	})
-	
+
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		logging.Fatal("Failed to connect to database", "host", cfg.Host, "error", err)
+	}
+
+	if err := db.Use(tracing.GORM()); err != nil {
+		logging.Fatal("Failed to install query tracing", "error", err)
 	}
 
 	// Get the underlying SQL DB
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatal("Failed to get database instance:", err)
+		logging.Fatal("Failed to get database instance", "error", err)
 	}
 
 	// Set connection pool settings
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetMaxOpenConns(100)
 
-	// Drop existing tables
-	err = db.Migrator().DropTable(&models.Experience{}, &models.Project{}, &models.SkillCategory{})
+	return db
+}
+
+// OpenSQLite opens the SQLite database at path with foreign keys enforced,
+// constraint violations reported as gorm errors and queries traced.
+func OpenSQLite(path string) (*gorm.DB, error) {
+	dsn := path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		TranslateError: true,
+	})
 	if err != nil {
-		log.Fatal("Failed to drop tables:", err)
+		return nil, err
+	}
+	if err := db.Use(tracing.GORM()); err != nil {
+		return nil, err
 	}
 
-	// Auto Migrate the schemas
-	err = db.AutoMigrate(
-		&models.Experience{},
-		&models.Project{},
-		&models.SkillCategory{},
-	)
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		return nil, err
 	}
 
-	DB = db
-	log.Println("Database connection established and migrations completed")
+	// SQLite allows one writer at a time, and every connection to ":memory:"
+	// would get its own empty database
+	sqlDB.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+// InitDatabase connects, applies pending migrations and, when SEED_ON_BOOT
+// is "true", seeds the database.
+func InitDatabase(cfg DatabaseConfig) {
+	ConnectDatabase(cfg)
+
+	// The memory driver has no schema to migrate
+	if DB != nil {
+		applied, err := migrations.Up(DB)
+		if err != nil {
+			logging.Fatal("Failed to migrate database", "error", err)
+		}
+		for _, m := range applied {
+			slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+		}
+		slog.Info("Database migrations completed", "applied", len(applied))
+	}
 
-	// Seed the database with initial data
-	SeedDatabase()
-} 
\ No newline at end of file
+	// Seeding is opt-in so restarts never touch content edited through the API
+	if os.Getenv("SEED_ON_BOOT") == "true" {
+		data, err := LoadSeedData(os.Getenv("SEED_FILE"))
+		if err != nil {
+			logging.Fatal("Failed to load seed data", "error", err)
+		}
+		report, err := SeedDatabase(data)
+		if err != nil {
+			logging.Fatal("Failed to seed database", "error", err)
+		}
+		LogSeedReport(report)
+	}
+}
+
+// CloseDatabase closes the connection pool, if there is one.
+func CloseDatabase() {
+	if DB == nil {
+		return
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		slog.Error("Failed to get database instance", "error", err)
+		return
+	}
+	if err := sqlDB.Close(); err != nil {
+		slog.Error("Failed to close database", "error", err)
+		return
+	}
+	slog.Info("Database connection closed")
+}
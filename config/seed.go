@@ -1,192 +1,241 @@
 package config
 
 import (
+	"context"
+	_ "embed"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+
+	"gorm.io/gorm"
 	"wannn-site-rebuild-api/models"
+	"wannn-site-rebuild-api/repository"
 )
 
-func SeedDatabase() {
-	// Seed Experiences
-	experiences := []models.Experience{
-		{
-			Title:   "New Venture & Technology Incubation Intern",
-			Company: "PT. XL Axiata Tbk",
-			Period:  "Sep 2024 - Dec 2024",
-			Description: mustJSON([]string{
-				"Led development of Roadinspex, a road damage detection system, implementing 15+ RESTful APIs with Sequelize ORM and JWT authentication",
-				"Designed comprehensive UML diagrams and conducted thorough testing (API, Unit, Integration, Functional) with detailed documentation",
-				"Optimized HelloMet safety monitoring system by migrating to Jetson Nano with SSD MobileNet, achieving 7x performance improvement",
-				"Developed WANalyze public transport dashboard using Home Assistant with Frigate for real-time occupancy monitoring",
-				"Contributed to Smart AC Automation project for Indomaret using Thingsboard, integrating IoT devices for temperature control",
-			}),
-		},
-		{
-			Title:   "Data Labeler",
-			Company: "Retrux Studio",
-			Period:  "Nov 2024 - Dec 2024",
-			Description: mustJSON([]string{
-				"Labeled and validated 200+ supermarket shelf images for stock availability detection, ensuring high-quality training data",
-				"Utilized labelImg for precise bounding box annotation and collaborated with a 5-member team on 1,000+ image dataset",
-				"Conducted peer reviews of annotations to maintain dataset accuracy and consistency",
-				"Enhanced ML development efficiency by providing clean, validated datasets that reduced validation workload",
-			}),
-		},
-		{
-			Title:   "Computer Vision",
-			Company: "Barunastra ITS RoboBoat Team",
-			Period:  "Jan 2023 - Dec 2024",
-			Description: mustJSON([]string{
-				"Developed vision-side pipeline for Autonomous Surface Vehicles (ASV), including object detection, tracking, and counting using YOLOv5",
-				"Implemented 2-step detection feature that improved buoy detection accuracy by 90% through color-based recognition",
-				"Optimized computer vision processing by migrating to edge devices, reducing power consumption by 46%",
-				"Managed all computer-related systems for extended ASV deployments, ensuring reliable operation",
-			}),
-		},
-	}
-
-	// Seed Projects
-	projects := []models.Project{
-		{
-			Title:       "Roadinspex",
-			Description: "A road damage detection system built with Node.js, Express, PostgreSQL, and Sequelize. This project is a part of my internship at PT. XL Axiata Tbk. I was responsible for developing the backend of the system, including the RESTful APIs and the database schema.",
-			Technologies: mustJSON([]string{
-				"Node.js",
-				"Express",
-				"PostgreSQL",
-				"Sequelize",
-				"JWT",
-			}),
-			Link: "https://roadinspex.xdevelopment.my.id/",
-		},
-		{
-			Title:       "MIoT (Multimedia and Internet of Things) Laboratorium Website",
-			Description: "A profile website of Multimedia and Internet of Things (MIoT) Laboratorium at Computer Engineering Department, Institut Teknologi Sepuluh Nopember. This project is our responsibility as Web Development Team in MIoT Laboratorium. I was responsible for developing the 10+ reusable components and 2 key pages, including the 'Practicums' page and the 'Our Researchs' page.",
-			Technologies: mustJSON([]string{
-				"React",
-				"Tailwind CSS",
-				"JavaScript",
-				"Framer Motion",
-				"React Router",
-				"React Icons",
-			}),
-			Link: "https://miot-lab.vercel.app/",
-		},
-		{
-			Title:       "Soil Monitoring Website",
-			Description: "A soil monitoring website built with Vite, React.js, Tailwind CSS, Express.js, and InfluxDB. This project is a part of my freelance as Web Developer. The key features are the real-time soil moisture (Nitrogen, pH, Phosphorus, Potassium) monitoring and the dashboard interface.",
-			Technologies: mustJSON([]string{
-				"Vite",
-				"React.js",
-				"Tailwind CSS",
-				"Express.js",
-				"InfluxDB",
-			}),
-			Link: "https://soilmonitor.my.id/",
-		},
-		{
-			Title:       "Water Level Monitoring Website",
-			Description: "A water level monitoring website built with Vite, Vue.js, Tailwind CSS, Express.js, and MongoDB. This project is a part of my freelance as Web Developer. The key features are the real-time water level monitoring, toggling, and automating the water pump.",
-			Technologies: mustJSON([]string{
-				"Vite",
-				"React.js",
-				"Tailwind CSS",
-				"Express.js",
-				"MongoDB",
-			}),
-			Link: "https://watermonitor.site/",
-		},
-		{
-			Title:       "YOLOv5-ROS2",
-			Description: "A ROS2 Humble Hawksbill package for object detection using YOLOv5. This project is a part of my job as a Computer Vision at Barunastra ITS RoboBoat Team. I was responsible for developing vision-side pipeline, including object detection, object tracking, and object counting utilizing YOLOv5 model.",
-			Technologies: mustJSON([]string{
-				"Python",
-				"ROS2",
-				"YOLOv5",
-				"OpenCV",
-				"PyTorch",
-			}),
-			Link: "https://github.com/wannn-one/yolov5-ros2",
-		},
-	}
-
-	// Seed Skill Categories
-	skillCategories := []models.SkillCategory{
-		{
-			Title: "Languages",
-			Skills: mustJSON([]string{
-				"C++",
-				"Python",
-				"JavaScript",
-				"Go",
-				"SQL",
-				"HTML/CSS",
-				"Shell Script",
-			}),
-		},
-		{
-			Title: "Frameworks & Libraries",
-			Skills: mustJSON([]string{
-				"React.js",
-				"Vue.js",
-				"Tailwind CSS",
-				"Express.js",
-				"Fiber",
-				"GORM",
-				"Sequelize",
-				"JWT",
-				"ROS2",
-				"PyTorch",
-				"OpenCV",
-			}),
-		},
-		{
-			Title: "DevOps & Tools",
-			Skills: mustJSON([]string{
-				"Docker",
-				"Git",
-				"GitHub",
-				"CI/CD",
-				"Linux",
-				"AWS",
-				"Visual Studio Code",
-				"Nginx",
-				"Apache2",
-				"Postman",
-			}),
-		},
-	}
-
-	// Insert seed data
-	for _, exp := range experiences {
-		result := DB.Create(&exp)
-		if result.Error != nil {
-			log.Printf("Error seeding experience: %v", result.Error)
+// defaultSeed is the portfolio content used when no seed file is given.
+//
+//go:embed seed.json
+var defaultSeed []byte
+
+// SeedData is the content of a seed file.
+type SeedData struct {
+	Experiences     []SeedExperience    `json:"experiences"`
+	Projects        []SeedProject       `json:"projects"`
+	SkillCategories []SeedSkillCategory `json:"skill_categories"`
+}
+
+type SeedExperience struct {
+	Title       string   `json:"title"`
+	Company     string   `json:"company"`
+	Period      string   `json:"period"`
+	Description []string `json:"description"`
+}
+
+type SeedProject struct {
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	Technologies []string `json:"technologies"`
+	Link         string   `json:"link"`
+}
+
+type SeedSkillCategory struct {
+	Title  string   `json:"title"`
+	Skills []string `json:"skills"`
+}
+
+// SeedResult counts what seeding did to one table.
+type SeedResult struct {
+	Created int
+	Updated int
+	Skipped int
+}
+
+// SeedReport holds the per-table results of a seed run.
+type SeedReport struct {
+	Experiences     SeedResult
+	Projects        SeedResult
+	SkillCategories SeedResult
+}
+
+// LoadSeedData reads seed content from a JSON file, or returns the embedded
+// default content when path is empty.
+func LoadSeedData(path string) (SeedData, error) {
+	raw := defaultSeed
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return SeedData{}, err
 		}
+		raw = b
 	}
 
-	for _, proj := range projects {
-		result := DB.Create(&proj)
-		if result.Error != nil {
-			log.Printf("Error seeding project: %v", result.Error)
-		}
+	var data SeedData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return SeedData{}, fmt.Errorf("parsing seed data: %w", err)
 	}
+	return data, nil
+}
 
-	for _, cat := range skillCategories {
-		result := DB.Create(&cat)
-		if result.Error != nil {
-			log.Printf("Error seeding skill category: %v", result.Error)
+// SeedDatabase upserts the seed content by natural key: title and company
+// for experiences, title for projects and skill categories. Rows that
+// already match are left alone, so running it repeatedly is safe. With a
+// SQL driver the whole run is one transaction.
+func SeedDatabase(data SeedData) (SeedReport, error) {
+	ctx := context.Background()
+	if DB == nil {
+		return seedRepositories(ctx, Repos, data)
+	}
+
+	var report SeedReport
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		report, err = seedRepositories(ctx, repository.NewGORM(tx), data)
+		return err
+	})
+	return report, err
+}
+
+func seedRepositories(ctx context.Context, repos repository.Repositories, data SeedData) (SeedReport, error) {
+	var report SeedReport
+	for _, seed := range data.Experiences {
+		if err := seedExperience(ctx, repos.Experiences, seed, &report.Experiences); err != nil {
+			return report, fmt.Errorf("seeding experience %q: %w", seed.Title, err)
+		}
+	}
+	for _, seed := range data.Projects {
+		if err := seedProject(ctx, repos.Projects, seed, &report.Projects); err != nil {
+			return report, fmt.Errorf("seeding project %q: %w", seed.Title, err)
+		}
+	}
+	for _, seed := range data.SkillCategories {
+		if err := seedSkillCategory(ctx, repos.SkillCategories, seed, &report.SkillCategories); err != nil {
+			return report, fmt.Errorf("seeding skill category %q: %w", seed.Title, err)
 		}
 	}
+	return report, nil
+}
 
-	log.Println("Database seeding completed")
+// LogSeedReport logs the created/updated/skipped counts of a seed run.
+func LogSeedReport(report SeedReport) {
+	counts := []struct {
+		entity string
+		result SeedResult
+	}{
+		{"experiences", report.Experiences},
+		{"projects", report.Projects},
+		{"skill_categories", report.SkillCategories},
+	}
+	for _, c := range counts {
+		slog.Info("Seeded content", "entity", c.entity,
+			"created", c.result.Created, "updated", c.result.Updated, "skipped", c.result.Skipped)
+	}
 }
 
-// Helper function to convert slice to JSON string
-func mustJSON(v interface{}) string {
-	b, err := json.Marshal(v)
+// createLast creates item after the last live record of its kind, so
+// seeded content keeps the order of the seed file.
+func createLast[T any](ctx context.Context, repo repository.CRUD[T], item *T, position *int) error {
+	next, err := repo.NextPosition(ctx)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	return string(b)
-} 
\ No newline at end of file
+	*position = next
+	return repo.Create(ctx, item)
+}
+
+func seedExperience(ctx context.Context, repo repository.ExperienceRepository, seed SeedExperience, result *SeedResult) error {
+	want := models.Experience{
+		Title:       seed.Title,
+		Company:     seed.Company,
+		Period:      seed.Period,
+		Description: seed.Description,
+	}
+
+	existing, err := repo.FindByTitleAndCompany(ctx, seed.Title, seed.Company)
+	if errors.Is(err, repository.ErrNotFound) {
+		result.Created++
+		return createLast(ctx, repo, &want, &want.Position)
+	}
+	if err != nil {
+		return err
+	}
+
+	if existing.Period == want.Period && slices.Equal(existing.Description, want.Description) {
+		result.Skipped++
+		return nil
+	}
+	existing.Period, existing.Description = want.Period, want.Description
+	result.Updated++
+	return repo.Update(ctx, existing)
+}
+
+func seedProject(ctx context.Context, repo repository.ProjectRepository, seed SeedProject, result *SeedResult) error {
+	want := models.Project{
+		Title:        seed.Title,
+		Description:  seed.Description,
+		Technologies: seed.Technologies,
+		Link:         seed.Link,
+	}
+
+	existing, err := repo.FindByTitle(ctx, seed.Title)
+	if errors.Is(err, repository.ErrNotFound) {
+		result.Created++
+		return createLast(ctx, repo, &want, &want.Position)
+	}
+	if err != nil {
+		return err
+	}
+
+	if existing.Description == want.Description && slices.Equal(existing.Technologies, want.Technologies) && existing.Link == want.Link {
+		result.Skipped++
+		return nil
+	}
+	existing.Description, existing.Technologies, existing.Link = want.Description, want.Technologies, want.Link
+	result.Updated++
+	return repo.Update(ctx, existing)
+}
+
+func seedSkillCategory(ctx context.Context, repo repository.SkillCategoryRepository, seed SeedSkillCategory, result *SeedResult) error {
+	want := models.SkillCategory{
+		Title:  seed.Title,
+		Skills: seed.Skills,
+	}
+
+	existing, err := repo.FindByTitle(ctx, seed.Title)
+	if errors.Is(err, repository.ErrNotFound) {
+		result.Created++
+		return createLast(ctx, repo, &want, &want.Position)
+	}
+	if err != nil {
+		return err
+	}
+
+	skills := mergeSkills(existing.Skills, want.Skills)
+	if slices.Equal(existing.Skills, skills) {
+		result.Skipped++
+		return nil
+	}
+	existing.Skills = skills
+	result.Updated++
+	return repo.Update(ctx, existing)
+}
+
+// mergeSkills is the seed's set of skills in the stored order, which may
+// have been changed through PUT /skills/:id/order: skills the seed drops
+// are removed and new ones follow in seed order.
+func mergeSkills(stored, seed models.StringArray) models.StringArray {
+	merged := make(models.StringArray, 0, len(seed))
+	for _, skill := range stored {
+		if slices.Contains(seed, skill) {
+			merged = append(merged, skill)
+		}
+	}
+	for _, skill := range seed {
+		if !slices.Contains(merged, skill) {
+			merged = append(merged, skill)
+		}
+	}
+	return merged
+}
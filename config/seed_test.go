@@ -0,0 +1,26 @@
+package config
+
+import (
+	"slices"
+	"testing"
+
+	"wannn-site-rebuild-api/models"
+)
+
+func TestMergeSkills(t *testing.T) {
+	tests := []struct {
+		name         string
+		stored, seed models.StringArray
+		want         models.StringArray
+	}{
+		{"unchanged", models.StringArray{"Go", "SQL"}, models.StringArray{"Go", "SQL"}, models.StringArray{"Go", "SQL"}},
+		{"reordered through the API", models.StringArray{"SQL", "Go"}, models.StringArray{"Go", "SQL"}, models.StringArray{"SQL", "Go"}},
+		{"new skills go last", models.StringArray{"SQL", "Go"}, models.StringArray{"Rust", "Go", "SQL"}, models.StringArray{"SQL", "Go", "Rust"}},
+		{"removed skills are dropped", models.StringArray{"SQL", "PHP", "Go"}, models.StringArray{"Go", "SQL"}, models.StringArray{"SQL", "Go"}},
+	}
+	for _, tt := range tests {
+		if got := mergeSkills(tt.stored, tt.seed); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"wannn-site-rebuild-api/apperr"
+	"wannn-site-rebuild-api/auth"
+	"wannn-site-rebuild-api/models"
+	"wannn-site-rebuild-api/repository"
+)
+
+// errLoginDisabled is returned by every endpoint when JWT is not configured.
+var errLoginDisabled = apperr.Unavailable("Login is disabled: JWT is not configured", nil)
+
+// errRefreshRejected is returned for refresh tokens that are unknown,
+// expired, revoked or reused.
+var errRefreshRejected = errors.New("refresh token rejected")
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// AuthHandler serves the login, refresh and logout endpoints.
+type AuthHandler struct {
+	Users         repository.UserRepository
+	RefreshTokens repository.RefreshTokenRepository
+	Tokens        *auth.Tokens
+	RefreshTTL    time.Duration
+}
+
+// NewAuthHandler returns an AuthHandler. tokens may be nil when JWT is not
+// configured, in which case every endpoint answers 503.
+func NewAuthHandler(users repository.UserRepository, refreshTokens repository.RefreshTokenRepository, tokens *auth.Tokens, refreshTTL time.Duration) *AuthHandler {
+	return &AuthHandler{Users: users, RefreshTokens: refreshTokens, Tokens: tokens, RefreshTTL: refreshTTL}
+}
+
+// UserSubject is the JWT subject identifying a user account.
+func UserSubject(id uint) string {
+	return "user:" + strconv.FormatUint(uint64(id), 10)
+}
+
+func (h *AuthHandler) Login(c *fiber.Ctx) error {
+	if h.Tokens == nil {
+		return errLoginDisabled
+	}
+
+	var req LoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperr.BadRequest("Invalid request body: " + err.Error())
+	}
+
+	user, err := h.Users.GetByEmail(c.UserContext(), normalizeEmail(req.Email))
+	if errors.Is(err, repository.ErrNotFound) {
+		user = &models.User{}
+	} else if err != nil {
+		return apperr.FromDB(err, "")
+	}
+	if !auth.CheckPassword(user.PasswordHash, req.Password) {
+		return apperr.Unauthorized("Invalid email or password")
+	}
+
+	response, err := h.issue(c, *user, uuid.NewString())
+	if err != nil {
+		return apperr.FromDB(err, "")
+	}
+	return c.JSON(response)
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token.
+// Each refresh token works once; presenting a used one means it leaked, so
+// every token descended from the same login is revoked.
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	if h.Tokens == nil {
+		return errLoginDisabled
+	}
+
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperr.BadRequest("Invalid request body: " + err.Error())
+	}
+	if req.RefreshToken == "" {
+		return apperr.Validation(map[string]string{"refresh_token": "is required"})
+	}
+
+	response, err := h.rotate(c, req.RefreshToken)
+	if errors.Is(err, errRefreshRejected) {
+		return apperr.Unauthorized("Invalid refresh token")
+	}
+	if err != nil {
+		return apperr.FromDB(err, "")
+	}
+	return c.JSON(response)
+}
+
+// rotate consumes a refresh token and issues its successor.
+func (h *AuthHandler) rotate(c *fiber.Ctx, refreshToken string) (TokenResponse, error) {
+	ctx := c.UserContext()
+	token, err := h.RefreshTokens.Consume(ctx, auth.HashToken(refreshToken))
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrTokenReused) {
+		return TokenResponse{}, errRefreshRejected
+	}
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return TokenResponse{}, errRefreshRejected
+	}
+
+	// Re-read the account so role changes and deletions take effect
+	user, err := h.Users.Get(ctx, token.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return TokenResponse{}, errRefreshRejected
+	}
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	return h.issue(c, *user, token.FamilyID)
+}
+
+// Logout revokes the session the given refresh token belongs to.
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperr.BadRequest("Invalid request body: " + err.Error())
+	}
+	if req.RefreshToken == "" {
+		return apperr.Validation(map[string]string{"refresh_token": "is required"})
+	}
+
+	if err := h.RefreshTokens.RevokeFamily(c.UserContext(), auth.HashToken(req.RefreshToken)); err != nil {
+		return apperr.FromDB(err, "")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// issue signs an access token and stores a new refresh token in family.
+func (h *AuthHandler) issue(c *fiber.Ctx, user models.User, family string) (TokenResponse, error) {
+	access, err := h.Tokens.Sign(UserSubject(user.ID), auth.Role(user.Role))
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	refresh, hash, err := auth.GenerateRefreshToken()
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	record := models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  family,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(h.RefreshTTL),
+	}
+	if err := h.RefreshTokens.Create(c.UserContext(), &record); err != nil {
+		return TokenResponse{}, err
+	}
+
+	return TokenResponse{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(h.Tokens.TTL().Seconds()),
+	}, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"wannn-site-rebuild-api/apperr"
+	"wannn-site-rebuild-api/repository"
+)
+
+// etag is the strong entity tag of a content record at a version.
+func etag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// sendRecord writes a content record with its ETag. A GET or HEAD whose
+// If-None-Match already names that ETag gets an empty 304 instead.
+func sendRecord(c *fiber.Ctx, version uint, body interface{}) error {
+	c.Set(fiber.HeaderETag, etag(version))
+	if (c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead) && c.Fresh() {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	return c.JSON(body)
+}
+
+// checkIfMatch requires an If-Match header naming the record's current
+// ETag, or *, so a client never overwrites a change it has not seen.
+func checkIfMatch(c *fiber.Ctx, version uint) error {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" {
+		return apperr.PreconditionRequired("Send If-Match with the ETag of the record being changed")
+	}
+	if header == "*" {
+		return nil
+	}
+	// If-Match uses strong comparison, so weak tags never match
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == current {
+			return nil
+		}
+	}
+	return errChanged()
+}
+
+// errChanged reports that the record changed after the client read it.
+func errChanged() error {
+	return apperr.PreconditionFailed("The record was changed since it was read; fetch it again and retry")
+}
+
+// fromVersionedWrite classifies errors from a content Update or Delete.
+func fromVersionedWrite(err error, notFoundDetail string) error {
+	if errors.Is(err, repository.ErrVersionMismatch) {
+		return errChanged()
+	}
+	return apperr.FromDB(err, notFoundDetail)
+}
@@ -1,172 +1,282 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"reflect"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
-	"wannn-site-rebuild-api/config"
+	"wannn-site-rebuild-api/apperr"
+	"wannn-site-rebuild-api/listing"
 	"wannn-site-rebuild-api/models"
+	"wannn-site-rebuild-api/repository"
+	"wannn-site-rebuild-api/validation"
 )
 
 type CreateExperienceRequest struct {
-	Title       string   `json:"title"`
-	Company     string   `json:"company"`
-	Period      string   `json:"period"`
-	Description []string `json:"description"`
+	Title       string   `json:"title" validate:"required,max=255"`
+	Company     string   `json:"company" validate:"required,max=255"`
+	Period      string   `json:"period" validate:"required,max=100"`
+	Description []string `json:"description" validate:"required,max=20,unique,itemrequired,itemmax=1000"`
+	// Position orders the experiences in lists. New experiences go last when
+	// it is left out; a PUT must send it, a PATCH keeps it unless set.
+	Position *int `json:"position" validate:"min=0"`
+}
+
+type ExperienceResponse struct {
+	ID          uint               `json:"id"`
+	Title       string             `json:"title"`
+	Company     string             `json:"company"`
+	Period      string             `json:"period"`
+	Description models.StringArray `json:"description"`
+	Position    int                `json:"position"`
+	Version     uint               `json:"version"`
+	DeletedAt   *time.Time         `json:"deleted_at,omitempty"`
+}
+
+// ExperienceHandler serves the /experiences endpoints.
+type ExperienceHandler struct {
+	Repo repository.ExperienceRepository
+}
+
+func NewExperienceHandler(repo repository.ExperienceRepository) *ExperienceHandler {
+	return &ExperienceHandler{Repo: repo}
+}
+
+func (h *ExperienceHandler) List(c *fiber.Ctx) error {
+	return h.list(c, h.Repo.List)
+}
+
+// Trash lists deleted experiences that have not been purged yet.
+func (h *ExperienceHandler) Trash(c *fiber.Ctx) error {
+	return h.list(c, h.Repo.Trash)
 }
 
-func GetExperiences(c *fiber.Ctx) error {
-	var experiences []models.Experience
-	result := config.DB.Find(&experiences)
-	if result.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": result.Error.Error(),
-		})
+func (h *ExperienceHandler) list(c *fiber.Ctx, fetch func(context.Context, listing.Query) ([]models.Experience, int64, error)) error {
+	q, err := listing.Parse(c, repository.ExperienceListing)
+	if err != nil {
+		return err
 	}
 
-	// Convert each experience's description back to array
-	type ExperienceResponse struct {
-		ID          uint     `json:"id"`
-		Title       string   `json:"title"`
-		Company     string   `json:"company"`
-		Period      string   `json:"period"`
-		Description []string `json:"description"`
+	experiences, total, err := fetch(c.UserContext(), q)
+	if err != nil {
+		return apperr.FromDB(err, "")
 	}
+	experiences, meta := listing.Finish(c, repository.ExperienceListing, q, experiences, total)
 
-	var response []ExperienceResponse
+	response := make([]ExperienceResponse, 0, len(experiences))
 	for _, exp := range experiences {
-		var desc []string
-		json.Unmarshal([]byte(exp.Description), &desc)
-		response = append(response, ExperienceResponse{
-			ID:          exp.ID,
-			Title:       exp.Title,
-			Company:     exp.Company,
-			Period:      exp.Period,
-			Description: desc,
-		})
-	}
-
-	return c.JSON(response)
+		response = append(response, experienceResponse(exp))
+	}
+
+	return c.JSON(fiber.Map{
+		"data": response,
+		"meta": meta,
+	})
 }
 
-func GetExperienceByID(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var experience models.Experience
-	result := config.DB.First(&experience, id)
-	if result.Error != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Experience not found",
-		})
+func (h *ExperienceHandler) Get(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
 	}
 
-	// Convert description back to array
-	var desc []string
-	json.Unmarshal([]byte(experience.Description), &desc)
+	experience, err := h.Repo.Get(c.UserContext(), id)
+	if err != nil {
+		return apperr.FromDB(err, "Experience not found")
+	}
 
-	return c.JSON(fiber.Map{
-		"id":          experience.ID,
-		"title":       experience.Title,
-		"company":     experience.Company,
-		"period":      experience.Period,
-		"description": desc,
-	})
+	return sendRecord(c, experience.Version, experienceResponse(*experience))
 }
 
-func CreateExperience(c *fiber.Ctx) error {
+func (h *ExperienceHandler) Create(c *fiber.Ctx) error {
 	var req CreateExperienceRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body: " + err.Error(),
-		})
+		return apperr.BadRequest("Invalid request body: " + err.Error())
+	}
+	if errs := validation.Validate(req); errs != nil {
+		return apperr.Validation(errs)
 	}
 
-	// Convert description array to JSON string
-	descJSON, err := json.Marshal(req.Description)
+	position, err := positionOrLast(c, req.Position, h.Repo.NextPosition)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to process description",
-		})
+		return err
 	}
 
 	experience := models.Experience{
 		Title:       req.Title,
 		Company:     req.Company,
 		Period:      req.Period,
-		Description: string(descJSON),
-	}
-
-	result := config.DB.Create(&experience)
-	if result.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": result.Error.Error(),
-		})
-	}
-
-	// Convert back to response format
-	var desc []string
-	json.Unmarshal([]byte(experience.Description), &desc)
-	
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"id":          experience.ID,
-		"title":       experience.Title,
-		"company":     experience.Company,
-		"period":      experience.Period,
-		"description": desc,
-	})
+		Description: req.Description,
+		Position:    position,
+	}
+
+	if err := h.Repo.Create(c.UserContext(), &experience); err != nil {
+		return apperr.FromDB(err, "")
+	}
+
+	c.Set(fiber.HeaderETag, etag(experience.Version))
+	return c.Status(fiber.StatusCreated).JSON(experienceResponse(experience))
 }
 
-func UpdateExperience(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var experience models.Experience
-	
-	// Check if experience exists
-	if err := config.DB.First(&experience, id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Experience not found",
-		})
+func (h *ExperienceHandler) Update(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
 	}
 
 	var req CreateExperienceRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return apperr.BadRequest("Invalid request body: " + err.Error())
+	}
+	if err := validatePut(req, req.Position); err != nil {
+		return err
 	}
 
-	// Convert description array to JSON string
-	descJSON, err := json.Marshal(req.Description)
+	// Check if experience exists
+	experience, err := h.Repo.Get(c.UserContext(), id)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to process description",
-		})
+		return apperr.FromDB(err, "Experience not found")
+	}
+	if err := checkIfMatch(c, experience.Version); err != nil {
+		return err
 	}
 
 	experience.Title = req.Title
 	experience.Company = req.Company
 	experience.Period = req.Period
-	experience.Description = string(descJSON)
+	experience.Description = req.Description
+	experience.Position = *req.Position
 
-	config.DB.Save(&experience)
+	if err := h.Repo.Update(c.UserContext(), experience); err != nil {
+		return fromVersionedWrite(err, "Experience not found")
+	}
 
-	// Convert back to response format
-	var desc []string
-	json.Unmarshal([]byte(experience.Description), &desc)
+	return sendRecord(c, experience.Version, experienceResponse(*experience))
+}
 
-	return c.JSON(fiber.Map{
-		"id":          experience.ID,
-		"title":       experience.Title,
-		"company":     experience.Company,
-		"period":      experience.Period,
-		"description": desc,
+// Patch changes only the fields the patch touches. PUT replaces the whole
+// experience.
+func (h *ExperienceHandler) Patch(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
+	}
+
+	experience, err := h.Repo.Get(c.UserContext(), id)
+	if err != nil {
+		return apperr.FromDB(err, "Experience not found")
+	}
+	if err := checkIfMatch(c, experience.Version); err != nil {
+		return err
+	}
+
+	req, err := applyPatch(c, CreateExperienceRequest{
+		Title:       experience.Title,
+		Company:     experience.Company,
+		Period:      experience.Period,
+		Description: experience.Description,
+		Position:    &experience.Position,
 	})
+	if err != nil {
+		return err
+	}
+
+	stored := *experience
+	experience.Title = req.Title
+	experience.Company = req.Company
+	experience.Period = req.Period
+	experience.Description = req.Description
+	if req.Position != nil {
+		experience.Position = *req.Position
+	}
+
+	// A patch that changes nothing is not a write and keeps the version
+	if reflect.DeepEqual(*experience, stored) {
+		return sendRecord(c, experience.Version, experienceResponse(*experience))
+	}
+
+	if err := h.Repo.Update(c.UserContext(), experience); err != nil {
+		return fromVersionedWrite(err, "Experience not found")
+	}
+
+	return sendRecord(c, experience.Version, experienceResponse(*experience))
 }
 
-func DeleteExperience(c *fiber.Ctx) error {
-	id := c.Params("id")
-	result := config.DB.Delete(&models.Experience{}, id)
-	if result.RowsAffected == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Experience not found",
-		})
+// Delete moves the experience to the trash. With ?hard=true it removes the
+// experience, live or already in the trash, for good.
+func (h *ExperienceHandler) Delete(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	hard, err := queryBool(c, "hard")
+	if err != nil {
+		return err
+	}
+
+	get, remove := h.Repo.Get, h.Repo.Delete
+	if hard {
+		get, remove = h.Repo.GetIncludingTrash, h.Repo.Purge
+	}
+
+	experience, err := get(c.UserContext(), id)
+	if err != nil {
+		return apperr.FromDB(err, "Experience not found")
+	}
+	if err := checkIfMatch(c, experience.Version); err != nil {
+		return err
+	}
+
+	if err := remove(c.UserContext(), id, experience.Version); err != nil {
+		return fromVersionedWrite(err, "Experience not found")
 	}
 	return c.SendStatus(fiber.StatusNoContent)
-} 
\ No newline at end of file
+}
+
+// Restore takes the experience out of the trash.
+func (h *ExperienceHandler) Restore(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
+	}
+
+	experience, err := h.Repo.Restore(c.UserContext(), id)
+	if err != nil {
+		return apperr.FromDB(err, "Experience not found in the trash")
+	}
+
+	return sendRecord(c, experience.Version, experienceResponse(*experience))
+}
+
+// Reorder sets the display order of every experience in one go.
+func (h *ExperienceHandler) Reorder(c *fiber.Ctx) error {
+	order, err := parseOrder(c)
+	if err != nil {
+		return err
+	}
+
+	experiences, err := h.Repo.Reorder(c.UserContext(), order)
+	if err != nil {
+		return fromReorder(err, "experience")
+	}
+
+	response := make([]ExperienceResponse, 0, len(experiences))
+	for _, item := range experiences {
+		response = append(response, experienceResponse(item))
+	}
+	return c.JSON(fiber.Map{"data": response})
+}
+
+func experienceResponse(e models.Experience) ExperienceResponse {
+	return ExperienceResponse{
+		ID:          e.ID,
+		Title:       e.Title,
+		Company:     e.Company,
+		Period:      e.Period,
+		Description: e.Description,
+		Position:    e.Position,
+		Version:     e.Version,
+		DeletedAt:   deletedAt(e.DeletedAt),
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"wannn-site-rebuild-api/logging"
+	"wannn-site-rebuild-api/migrations"
+	"wannn-site-rebuild-api/version"
+)
+
+// readyTimeout bounds each readiness check, so a hung database fails the
+// probe instead of stalling it.
+const readyTimeout = 2 * time.Second
+
+// HealthHandler serves the liveness, readiness and version endpoints.
+type HealthHandler struct {
+	// DB is the SQL database behind the repositories, nil for the memory
+	// driver.
+	DB *gorm.DB
+}
+
+func NewHealthHandler(db *gorm.DB) *HealthHandler {
+	return &HealthHandler{DB: db}
+}
+
+// Health answers GET /healthz. It only reports that the process is up and
+// serving requests.
+func (h *HealthHandler) Health(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// Ready answers GET /readyz with 200 when the database answers a ping and
+// every migration is applied, and 503 with the failing checks otherwise.
+// The endpoint is public, so failures are logged and reported without the
+// underlying error.
+func (h *HealthHandler) Ready(c *fiber.Ctx) error {
+	checks := fiber.Map{}
+	ready := true
+
+	if h.DB == nil {
+		checks["database"] = "skipped: no SQL database"
+		checks["migrations"] = "skipped: no SQL database"
+	} else {
+		ctx, cancel := context.WithTimeout(c.UserContext(), readyTimeout)
+		defer cancel()
+
+		if err := h.ping(ctx); err != nil {
+			ready = false
+			logging.FromContext(c.UserContext()).Error("Readiness check: database ping failed", "error", err)
+			checks["database"] = "unreachable"
+			checks["migrations"] = "skipped: database unreachable"
+		} else {
+			checks["database"] = "ok"
+			pending, err := migrations.Pending(h.DB.WithContext(ctx))
+			switch {
+			case err != nil:
+				ready = false
+				logging.FromContext(c.UserContext()).Error("Readiness check: listing pending migrations failed", "error", err)
+				checks["migrations"] = "unknown"
+			case len(pending) > 0:
+				ready = false
+				checks["migrations"] = strconv.Itoa(len(pending)) + " pending, run migrate up"
+			default:
+				checks["migrations"] = "ok"
+			}
+		}
+	}
+
+	status := "ok"
+	if !ready {
+		status = "unavailable"
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+	return c.JSON(fiber.Map{"status": status, "checks": checks})
+}
+
+// Version answers GET /version with the build commit and time.
+func (h *HealthHandler) Version(c *fiber.Ctx) error {
+	return c.JSON(version.Get())
+}
+
+func (h *HealthHandler) ping(ctx context.Context) error {
+	sqlDB, err := h.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
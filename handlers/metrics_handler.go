@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"wannn-site-rebuild-api/logging"
+	"wannn-site-rebuild-api/metrics"
+	"wannn-site-rebuild-api/repository"
+)
+
+// MetricsHandler serves GET /metrics in the Prometheus text format.
+type MetricsHandler struct {
+	HTTP  *metrics.HTTP
+	Repos repository.Repositories
+	// DB is the SQL database behind Repos, nil for the memory driver.
+	DB *gorm.DB
+}
+
+func NewMetricsHandler(httpMetrics *metrics.HTTP, repos repository.Repositories, db *gorm.DB) *MetricsHandler {
+	return &MetricsHandler{HTTP: httpMetrics, Repos: repos, DB: db}
+}
+
+// Metrics renders request metrics, database pool statistics and the number
+// of rows of each content type.
+func (h *MetricsHandler) Metrics(c *fiber.Ctx) error {
+	var w metrics.Writer
+	h.HTTP.WriteTo(&w)
+	if h.DB != nil {
+		if sqlDB, err := h.DB.DB(); err == nil {
+			writePoolStats(&w, sqlDB.Stats())
+		}
+	}
+	h.writeRowCounts(c.UserContext(), &w)
+
+	c.Set(fiber.HeaderContentType, metrics.ContentType)
+	return c.SendString(w.String())
+}
+
+func writePoolStats(w *metrics.Writer, s sql.DBStats) {
+	gauges := []struct {
+		name, help string
+		value      float64
+	}{
+		{"wandhx_db_max_open_connections", "Maximum number of open connections to the database.", float64(s.MaxOpenConnections)},
+		{"wandhx_db_open_connections", "Established connections, both in use and idle.", float64(s.OpenConnections)},
+		{"wandhx_db_in_use_connections", "Connections currently in use.", float64(s.InUse)},
+		{"wandhx_db_idle_connections", "Idle connections.", float64(s.Idle)},
+	}
+	for _, g := range gauges {
+		w.Help(g.name, "gauge", g.help)
+		w.Sample(g.name, nil, g.value)
+	}
+
+	counters := []struct {
+		name, help string
+		value      float64
+	}{
+		{"wandhx_db_wait_count_total", "Connections waited for.", float64(s.WaitCount)},
+		{"wandhx_db_wait_duration_seconds_total", "Time spent waiting for a connection.", s.WaitDuration.Seconds()},
+		{"wandhx_db_max_idle_closed_total", "Connections closed because of the idle pool limit.", float64(s.MaxIdleClosed)},
+		{"wandhx_db_max_idle_time_closed_total", "Connections closed because they were idle too long.", float64(s.MaxIdleTimeClosed)},
+		{"wandhx_db_max_lifetime_closed_total", "Connections closed because they reached their maximum lifetime.", float64(s.MaxLifetimeClosed)},
+	}
+	for _, g := range counters {
+		w.Help(g.name, "counter", g.help)
+		w.Sample(g.name, nil, g.value)
+	}
+}
+
+// writeRowCounts writes the live row count of each content type. A count
+// that fails is left out rather than failing the whole scrape.
+func (h *MetricsHandler) writeRowCounts(ctx context.Context, w *metrics.Writer) {
+	entities := []struct {
+		name  string
+		count func(context.Context) (int64, error)
+	}{
+		{"experiences", h.Repos.Experiences.Count},
+		{"projects", h.Repos.Projects.Count},
+		{"skill_categories", h.Repos.SkillCategories.Count},
+	}
+
+	w.Help("wandhx_rows", "gauge", "Live rows, by entity.")
+	for _, e := range entities {
+		n, err := e.count(ctx)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Counting rows for metrics failed", "entity", e.name, "error", err)
+			continue
+		}
+		w.Sample("wandhx_rows", metrics.Labels{"entity", e.name}, float64(n))
+	}
+}
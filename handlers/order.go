@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"wannn-site-rebuild-api/apperr"
+	"wannn-site-rebuild-api/repository"
+	"wannn-site-rebuild-api/validation"
+)
+
+// OrderRequest lists every live record in its new display order, each
+// with the version the client last read, so a reorder never overrides a
+// change the client has not seen.
+type OrderRequest struct {
+	Items []OrderItem `json:"items" validate:"required"`
+}
+
+// OrderItem is one record of an OrderRequest.
+type OrderItem struct {
+	ID      uint `json:"id"`
+	Version uint `json:"version"`
+}
+
+// parseOrder reads an OrderRequest. Every item must carry its version.
+func parseOrder(c *fiber.Ctx) ([]repository.OrderItem, error) {
+	var req OrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, apperr.BadRequest("Invalid request body: " + err.Error())
+	}
+	if errs := validation.Validate(req); errs != nil {
+		return nil, apperr.Validation(errs)
+	}
+	order := make([]repository.OrderItem, 0, len(req.Items))
+	seen := make(map[uint]bool, len(req.Items))
+	for _, item := range req.Items {
+		if seen[item.ID] {
+			return nil, apperr.Validation(map[string]string{
+				"items": fmt.Sprintf("must not contain duplicates (%d appears more than once)", item.ID),
+			})
+		}
+		seen[item.ID] = true
+		if item.Version == 0 {
+			return nil, apperr.PreconditionRequired("Send the version of every item, as read from the list")
+		}
+		order = append(order, repository.OrderItem{ID: item.ID, Version: item.Version})
+	}
+	return order, nil
+}
+
+// fromReorder classifies errors from a Reorder. An order that does not
+// match the stored records, or their versions, is based on a stale list.
+func fromReorder(err error, resource string) error {
+	switch {
+	case errors.Is(err, repository.ErrOrderMismatch):
+		return apperr.Conflict("items must list every " + resource + " exactly once; fetch the list again and retry")
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return apperr.PreconditionFailed("An item was changed since the list was read; fetch the list again and retry")
+	}
+	return apperr.FromDB(err, "")
+}
+
+// validatePut validates the body of a PUT. A PUT replaces the whole
+// record, so unlike a create, which puts a new record last, it must carry
+// the position.
+func validatePut(req interface{}, position *int) error {
+	errs := validation.Validate(req)
+	if position == nil {
+		if errs == nil {
+			errs = validation.Errors{}
+		}
+		errs["position"] = "is required"
+	}
+	if errs != nil {
+		return apperr.Validation(errs)
+	}
+	return nil
+}
+
+// positionOrLast returns the requested position, or the position after the
+// last record when the request has none.
+func positionOrLast(c *fiber.Ctx, requested *int, next func(context.Context) (int, error)) (int, error) {
+	if requested != nil {
+		return *requested, nil
+	}
+	position, err := next(c.UserContext())
+	if err != nil {
+		return 0, apperr.FromDB(err, "")
+	}
+	return position, nil
+}
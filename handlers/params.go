@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"wannn-site-rebuild-api/apperr"
+)
+
+// parseID reads the :id route parameter as a positive integer.
+func parseID(c *fiber.Ctx) (uint, error) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil || id == 0 {
+		return 0, apperr.BadRequest("Invalid id " + strconv.Quote(c.Params("id")))
+	}
+	return uint(id), nil
+}
+
+// queryBool reads an optional boolean query parameter, false when absent.
+func queryBool(c *fiber.Ctx, key string) (bool, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, apperr.BadRequest("Invalid " + key + " " + strconv.Quote(raw) + ", want true or false")
+	}
+	return value, nil
+}
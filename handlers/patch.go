@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+
+	"github.com/gofiber/fiber/v2"
+	"wannn-site-rebuild-api/apperr"
+	"wannn-site-rebuild-api/patch"
+	"wannn-site-rebuild-api/validation"
+)
+
+// applyPatch applies the request body to current, the record's writable
+// fields in the same shape PUT takes, and validates the result. The body is
+// a merge patch or a JSON patch depending on its Content-Type.
+func applyPatch[T any](c *fiber.Ctx, current T) (T, error) {
+	var patched T
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return patched, apperr.Internal("Failed to encode the record", err)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	result, err := patch.Apply(mediaType, doc, c.Body())
+	var patchErr *patch.Error
+	switch {
+	case errors.Is(err, patch.ErrUnsupportedMediaType):
+		return patched, apperr.UnsupportedMediaType("PATCH bodies must be " + patch.MIMEMergePatch + " or " + patch.MIMEJSONPatch)
+	case errors.As(err, &patchErr) && patchErr.Conflict:
+		return patched, apperr.Conflict("Patch does not apply: " + patchErr.Error())
+	case errors.As(err, &patchErr):
+		return patched, apperr.BadRequest("Invalid patch: " + patchErr.Error())
+	case err != nil:
+		return patched, apperr.Internal("Failed to apply the patch", err)
+	}
+
+	// Unknown members would otherwise be dropped silently, e.g. a patch
+	// setting "id" or a misspelled field
+	dec := json.NewDecoder(bytes.NewReader(result))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&patched); err != nil {
+		return patched, apperr.BadRequest("Invalid patched record: " + err.Error())
+	}
+	if errs := validation.Validate(patched); errs != nil {
+		return patched, apperr.Validation(errs)
+	}
+	return patched, nil
+}
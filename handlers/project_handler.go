@@ -1,172 +1,282 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"reflect"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
-	"wannn-site-rebuild-api/config"
+	"wannn-site-rebuild-api/apperr"
+	"wannn-site-rebuild-api/listing"
 	"wannn-site-rebuild-api/models"
+	"wannn-site-rebuild-api/repository"
+	"wannn-site-rebuild-api/validation"
 )
 
 type CreateProjectRequest struct {
-	Title        string   `json:"title"`
-	Description  string   `json:"description"`
-	Technologies []string `json:"technologies"`
-	Link         string   `json:"link"`
+	Title        string   `json:"title" validate:"required,max=255"`
+	Description  string   `json:"description" validate:"required,max=5000"`
+	Technologies []string `json:"technologies" validate:"required,max=50,unique,itemrequired,itemmax=100"`
+	Link         string   `json:"link" validate:"max=255,url"`
+	// Position orders the projects in lists. New projects go last when
+	// it is left out; a PUT must send it, a PATCH keeps it unless set.
+	Position *int `json:"position" validate:"min=0"`
+}
+
+type ProjectResponse struct {
+	ID           uint               `json:"id"`
+	Title        string             `json:"title"`
+	Description  string             `json:"description"`
+	Technologies models.StringArray `json:"technologies"`
+	Link         string             `json:"link"`
+	Position     int                `json:"position"`
+	Version      uint               `json:"version"`
+	DeletedAt    *time.Time         `json:"deleted_at,omitempty"`
+}
+
+// ProjectHandler serves the /projects endpoints.
+type ProjectHandler struct {
+	Repo repository.ProjectRepository
+}
+
+func NewProjectHandler(repo repository.ProjectRepository) *ProjectHandler {
+	return &ProjectHandler{Repo: repo}
+}
+
+func (h *ProjectHandler) List(c *fiber.Ctx) error {
+	return h.list(c, h.Repo.List)
+}
+
+// Trash lists deleted projects that have not been purged yet.
+func (h *ProjectHandler) Trash(c *fiber.Ctx) error {
+	return h.list(c, h.Repo.Trash)
 }
 
-func GetProjects(c *fiber.Ctx) error {
-	var projects []models.Project
-	result := config.DB.Find(&projects)
-	if result.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": result.Error.Error(),
-		})
+func (h *ProjectHandler) list(c *fiber.Ctx, fetch func(context.Context, listing.Query) ([]models.Project, int64, error)) error {
+	q, err := listing.Parse(c, repository.ProjectListing)
+	if err != nil {
+		return err
 	}
 
-	// Convert each project's technologies back to array
-	type ProjectResponse struct {
-		ID           uint     `json:"id"`
-		Title        string   `json:"title"`
-		Description  string   `json:"description"`
-		Technologies []string `json:"technologies"`
-		Link         string   `json:"link"`
+	projects, total, err := fetch(c.UserContext(), q)
+	if err != nil {
+		return apperr.FromDB(err, "")
 	}
+	projects, meta := listing.Finish(c, repository.ProjectListing, q, projects, total)
 
-	var response []ProjectResponse
+	response := make([]ProjectResponse, 0, len(projects))
 	for _, proj := range projects {
-		var tech []string
-		json.Unmarshal([]byte(proj.Technologies), &tech)
-		response = append(response, ProjectResponse{
-			ID:           proj.ID,
-			Title:        proj.Title,
-			Description:  proj.Description,
-			Technologies: tech,
-			Link:         proj.Link,
-		})
-	}
-
-	return c.JSON(response)
+		response = append(response, projectResponse(proj))
+	}
+
+	return c.JSON(fiber.Map{
+		"data": response,
+		"meta": meta,
+	})
 }
 
-func GetProjectByID(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var project models.Project
-	result := config.DB.First(&project, id)
-	if result.Error != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
+func (h *ProjectHandler) Get(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
 	}
 
-	// Convert technologies back to array
-	var tech []string
-	json.Unmarshal([]byte(project.Technologies), &tech)
+	project, err := h.Repo.Get(c.UserContext(), id)
+	if err != nil {
+		return apperr.FromDB(err, "Project not found")
+	}
 
-	return c.JSON(fiber.Map{
-		"id":           project.ID,
-		"title":        project.Title,
-		"description":  project.Description,
-		"technologies": tech,
-		"link":         project.Link,
-	})
+	return sendRecord(c, project.Version, projectResponse(*project))
 }
 
-func CreateProject(c *fiber.Ctx) error {
+func (h *ProjectHandler) Create(c *fiber.Ctx) error {
 	var req CreateProjectRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body: " + err.Error(),
-		})
+		return apperr.BadRequest("Invalid request body: " + err.Error())
+	}
+	if errs := validation.Validate(req); errs != nil {
+		return apperr.Validation(errs)
 	}
 
-	// Convert technologies array to JSON string
-	techJSON, err := json.Marshal(req.Technologies)
+	position, err := positionOrLast(c, req.Position, h.Repo.NextPosition)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to process technologies",
-		})
+		return err
 	}
 
 	project := models.Project{
 		Title:        req.Title,
 		Description:  req.Description,
-		Technologies: string(techJSON),
+		Technologies: req.Technologies,
 		Link:         req.Link,
+		Position:     position,
 	}
 
-	result := config.DB.Create(&project)
-	if result.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": result.Error.Error(),
-		})
+	if err := h.Repo.Create(c.UserContext(), &project); err != nil {
+		return apperr.FromDB(err, "")
 	}
 
-	// Convert back to response format
-	var tech []string
-	json.Unmarshal([]byte(project.Technologies), &tech)
-	
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"id":           project.ID,
-		"title":        project.Title,
-		"description":  project.Description,
-		"technologies": tech,
-		"link":         project.Link,
-	})
+	c.Set(fiber.HeaderETag, etag(project.Version))
+	return c.Status(fiber.StatusCreated).JSON(projectResponse(project))
 }
 
-func UpdateProject(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var project models.Project
-	
-	// Check if project exists
-	if err := config.DB.First(&project, id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
+func (h *ProjectHandler) Update(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
 	}
 
 	var req CreateProjectRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return apperr.BadRequest("Invalid request body: " + err.Error())
+	}
+	if err := validatePut(req, req.Position); err != nil {
+		return err
 	}
 
-	// Convert technologies array to JSON string
-	techJSON, err := json.Marshal(req.Technologies)
+	// Check if project exists
+	project, err := h.Repo.Get(c.UserContext(), id)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to process technologies",
-		})
+		return apperr.FromDB(err, "Project not found")
+	}
+	if err := checkIfMatch(c, project.Version); err != nil {
+		return err
 	}
 
 	project.Title = req.Title
 	project.Description = req.Description
-	project.Technologies = string(techJSON)
+	project.Technologies = req.Technologies
 	project.Link = req.Link
+	project.Position = *req.Position
 
-	config.DB.Save(&project)
+	if err := h.Repo.Update(c.UserContext(), project); err != nil {
+		return fromVersionedWrite(err, "Project not found")
+	}
 
-	// Convert back to response format
-	var tech []string
-	json.Unmarshal([]byte(project.Technologies), &tech)
+	return sendRecord(c, project.Version, projectResponse(*project))
+}
 
-	return c.JSON(fiber.Map{
-		"id":           project.ID,
-		"title":        project.Title,
-		"description":  project.Description,
-		"technologies": tech,
-		"link":         project.Link,
+// Patch changes only the fields the patch touches. PUT replaces the whole
+// project.
+func (h *ProjectHandler) Patch(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
+	}
+
+	project, err := h.Repo.Get(c.UserContext(), id)
+	if err != nil {
+		return apperr.FromDB(err, "Project not found")
+	}
+	if err := checkIfMatch(c, project.Version); err != nil {
+		return err
+	}
+
+	req, err := applyPatch(c, CreateProjectRequest{
+		Title:        project.Title,
+		Description:  project.Description,
+		Technologies: project.Technologies,
+		Link:         project.Link,
+		Position:     &project.Position,
 	})
+	if err != nil {
+		return err
+	}
+
+	stored := *project
+	project.Title = req.Title
+	project.Description = req.Description
+	project.Technologies = req.Technologies
+	project.Link = req.Link
+	if req.Position != nil {
+		project.Position = *req.Position
+	}
+
+	// A patch that changes nothing is not a write and keeps the version
+	if reflect.DeepEqual(*project, stored) {
+		return sendRecord(c, project.Version, projectResponse(*project))
+	}
+
+	if err := h.Repo.Update(c.UserContext(), project); err != nil {
+		return fromVersionedWrite(err, "Project not found")
+	}
+
+	return sendRecord(c, project.Version, projectResponse(*project))
 }
 
-func DeleteProject(c *fiber.Ctx) error {
-	id := c.Params("id")
-	result := config.DB.Delete(&models.Project{}, id)
-	if result.RowsAffected == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Project not found",
-		})
+// Delete moves the project to the trash. With ?hard=true it removes the
+// project, live or already in the trash, for good.
+func (h *ProjectHandler) Delete(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	hard, err := queryBool(c, "hard")
+	if err != nil {
+		return err
+	}
+
+	get, remove := h.Repo.Get, h.Repo.Delete
+	if hard {
+		get, remove = h.Repo.GetIncludingTrash, h.Repo.Purge
+	}
+
+	project, err := get(c.UserContext(), id)
+	if err != nil {
+		return apperr.FromDB(err, "Project not found")
+	}
+	if err := checkIfMatch(c, project.Version); err != nil {
+		return err
+	}
+
+	if err := remove(c.UserContext(), id, project.Version); err != nil {
+		return fromVersionedWrite(err, "Project not found")
 	}
 	return c.SendStatus(fiber.StatusNoContent)
-} 
\ No newline at end of file
+}
+
+// Restore takes the project out of the trash.
+func (h *ProjectHandler) Restore(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
+	}
+
+	project, err := h.Repo.Restore(c.UserContext(), id)
+	if err != nil {
+		return apperr.FromDB(err, "Project not found in the trash")
+	}
+
+	return sendRecord(c, project.Version, projectResponse(*project))
+}
+
+// Reorder sets the display order of every project in one go.
+func (h *ProjectHandler) Reorder(c *fiber.Ctx) error {
+	order, err := parseOrder(c)
+	if err != nil {
+		return err
+	}
+
+	projects, err := h.Repo.Reorder(c.UserContext(), order)
+	if err != nil {
+		return fromReorder(err, "project")
+	}
+
+	response := make([]ProjectResponse, 0, len(projects))
+	for _, item := range projects {
+		response = append(response, projectResponse(item))
+	}
+	return c.JSON(fiber.Map{"data": response})
+}
+
+func projectResponse(p models.Project) ProjectResponse {
+	return ProjectResponse{
+		ID:           p.ID,
+		Title:        p.Title,
+		Description:  p.Description,
+		Technologies: p.Technologies,
+		Link:         p.Link,
+		Position:     p.Position,
+		Version:      p.Version,
+		DeletedAt:    deletedAt(p.DeletedAt),
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"wannn-site-rebuild-api/apperr"
+	"wannn-site-rebuild-api/repository"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	maxSearchQueryLen  = 200
+)
+
+// searchTypes are the values accepted by ?type= on /search.
+var searchTypes = map[string]bool{"project": true, "experience": true, "skill": true}
+
+// SearchHandler serves GET /search.
+type SearchHandler struct {
+	Repo repository.SearchRepository
+}
+
+func NewSearchHandler(repo repository.SearchRepository) *SearchHandler {
+	return &SearchHandler{Repo: repo}
+}
+
+// Search answers GET /search?q=&type=&limit= with hits from every content
+// type, best match first.
+func (h *SearchHandler) Search(c *fiber.Ctx) error {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		return apperr.Validation(map[string]string{"q": "is required"})
+	}
+	if len(q) > maxSearchQueryLen {
+		return apperr.Validation(map[string]string{"q": "must be at most " + strconv.Itoa(maxSearchQueryLen) + " characters"})
+	}
+
+	limit := defaultSearchLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			return apperr.BadRequest("limit must be between 1 and " + strconv.Itoa(maxSearchLimit))
+		}
+		limit = n
+	}
+
+	types := []string{"project", "experience", "skill"}
+	if v := c.Query("type"); v != "" {
+		types = nil
+		for _, t := range strings.Split(v, ",") {
+			t = strings.TrimSpace(t)
+			if !searchTypes[t] {
+				return apperr.BadRequest("type must be a comma separated list of project, experience and skill")
+			}
+			types = append(types, t)
+		}
+	}
+
+	results, err := h.Repo.Search(c.UserContext(), q, types, limit)
+	if err != nil {
+		return apperr.FromDB(err, "")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": results,
+		"meta": fiber.Map{"query": q, "count": len(results)},
+	})
+}
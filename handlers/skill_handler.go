@@ -1,156 +1,328 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"reflect"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
-	"wannn-site-rebuild-api/config"
+	"wannn-site-rebuild-api/apperr"
+	"wannn-site-rebuild-api/listing"
 	"wannn-site-rebuild-api/models"
+	"wannn-site-rebuild-api/repository"
+	"wannn-site-rebuild-api/validation"
 )
 
 type CreateSkillCategoryRequest struct {
-	Title  string   `json:"title"`
-	Skills []string `json:"skills"`
+	Title  string   `json:"title" validate:"required,max=255"`
+	Skills []string `json:"skills" validate:"required,max=100,unique,itemrequired,itemmax=100"`
+	// Position orders the categories in lists. New categories go last when
+	// it is left out; a PUT must send it, a PATCH keeps it unless set.
+	Position *int `json:"position" validate:"min=0"`
+}
+
+// OrderSkillsRequest lists every skill of a category in its new display
+// order.
+type OrderSkillsRequest struct {
+	Skills []string `json:"skills" validate:"required,unique"`
+}
+
+type SkillCategoryResponse struct {
+	ID        uint               `json:"id"`
+	Title     string             `json:"title"`
+	Skills    models.StringArray `json:"skills"`
+	Position  int                `json:"position"`
+	Version   uint               `json:"version"`
+	DeletedAt *time.Time         `json:"deleted_at,omitempty"`
+}
+
+// SkillCategoryHandler serves the /skills endpoints.
+type SkillCategoryHandler struct {
+	Repo repository.SkillCategoryRepository
+}
+
+func NewSkillCategoryHandler(repo repository.SkillCategoryRepository) *SkillCategoryHandler {
+	return &SkillCategoryHandler{Repo: repo}
+}
+
+func (h *SkillCategoryHandler) List(c *fiber.Ctx) error {
+	return h.list(c, h.Repo.List)
+}
+
+// Trash lists deleted skill categories that have not been purged yet.
+func (h *SkillCategoryHandler) Trash(c *fiber.Ctx) error {
+	return h.list(c, h.Repo.Trash)
 }
 
-func GetSkillCategories(c *fiber.Ctx) error {
-	var categories []models.SkillCategory
-	result := config.DB.Find(&categories)
-	if result.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": result.Error.Error(),
-		})
+func (h *SkillCategoryHandler) list(c *fiber.Ctx, fetch func(context.Context, listing.Query) ([]models.SkillCategory, int64, error)) error {
+	q, err := listing.Parse(c, repository.SkillCategoryListing)
+	if err != nil {
+		return err
 	}
 
-	// Convert each category's skills back to array
-	type SkillCategoryResponse struct {
-		ID     uint     `json:"id"`
-		Title  string   `json:"title"`
-		Skills []string `json:"skills"`
+	categories, total, err := fetch(c.UserContext(), q)
+	if err != nil {
+		return apperr.FromDB(err, "")
 	}
+	categories, meta := listing.Finish(c, repository.SkillCategoryListing, q, categories, total)
 
-	var response []SkillCategoryResponse
+	response := make([]SkillCategoryResponse, 0, len(categories))
 	for _, cat := range categories {
-		var skills []string
-		json.Unmarshal([]byte(cat.Skills), &skills)
-		response = append(response, SkillCategoryResponse{
-			ID:     cat.ID,
-			Title:  cat.Title,
-			Skills: skills,
-		})
+		response = append(response, skillCategoryResponse(cat))
 	}
 
-	return c.JSON(response)
+	return c.JSON(fiber.Map{
+		"data": response,
+		"meta": meta,
+	})
 }
 
-func GetSkillCategoryByID(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var category models.SkillCategory
-	result := config.DB.First(&category, id)
-	if result.Error != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Skill category not found",
-		})
+func (h *SkillCategoryHandler) Get(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
 	}
 
-	// Convert skills back to array
-	var skills []string
-	json.Unmarshal([]byte(category.Skills), &skills)
+	category, err := h.Repo.Get(c.UserContext(), id)
+	if err != nil {
+		return apperr.FromDB(err, "Skill category not found")
+	}
 
-	return c.JSON(fiber.Map{
-		"id":     category.ID,
-		"title":  category.Title,
-		"skills": skills,
-	})
+	return sendRecord(c, category.Version, skillCategoryResponse(*category))
 }
 
-func CreateSkillCategory(c *fiber.Ctx) error {
+func (h *SkillCategoryHandler) Create(c *fiber.Ctx) error {
 	var req CreateSkillCategoryRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body: " + err.Error(),
-		})
+		return apperr.BadRequest("Invalid request body: " + err.Error())
+	}
+	if errs := validation.Validate(req); errs != nil {
+		return apperr.Validation(errs)
 	}
 
-	// Convert skills array to JSON string
-	skillsJSON, err := json.Marshal(req.Skills)
+	position, err := positionOrLast(c, req.Position, h.Repo.NextPosition)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to process skills",
-		})
+		return err
 	}
 
 	category := models.SkillCategory{
-		Title:  req.Title,
-		Skills: string(skillsJSON),
+		Title:    req.Title,
+		Skills:   req.Skills,
+		Position: position,
 	}
 
-	result := config.DB.Create(&category)
-	if result.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": result.Error.Error(),
-		})
+	if err := h.Repo.Create(c.UserContext(), &category); err != nil {
+		return apperr.FromDB(err, "")
 	}
 
-	// Convert back to response format
-	var skills []string
-	json.Unmarshal([]byte(category.Skills), &skills)
-	
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"id":     category.ID,
-		"title":  category.Title,
-		"skills": skills,
-	})
+	c.Set(fiber.HeaderETag, etag(category.Version))
+	return c.Status(fiber.StatusCreated).JSON(skillCategoryResponse(category))
 }
 
-func UpdateSkillCategory(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var category models.SkillCategory
-	
-	// Check if category exists
-	if err := config.DB.First(&category, id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Skill category not found",
-		})
+func (h *SkillCategoryHandler) Update(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
 	}
 
 	var req CreateSkillCategoryRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return apperr.BadRequest("Invalid request body: " + err.Error())
+	}
+	if err := validatePut(req, req.Position); err != nil {
+		return err
 	}
 
-	// Convert skills array to JSON string
-	skillsJSON, err := json.Marshal(req.Skills)
+	// Check if category exists
+	category, err := h.Repo.Get(c.UserContext(), id)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to process skills",
-		})
+		return apperr.FromDB(err, "Skill category not found")
+	}
+	if err := checkIfMatch(c, category.Version); err != nil {
+		return err
 	}
 
 	category.Title = req.Title
-	category.Skills = string(skillsJSON)
+	category.Skills = req.Skills
+	category.Position = *req.Position
 
-	config.DB.Save(&category)
+	if err := h.Repo.Update(c.UserContext(), category); err != nil {
+		return fromVersionedWrite(err, "Skill category not found")
+	}
 
-	// Convert back to response format
-	var skills []string
-	json.Unmarshal([]byte(category.Skills), &skills)
+	return sendRecord(c, category.Version, skillCategoryResponse(*category))
+}
 
-	return c.JSON(fiber.Map{
-		"id":     category.ID,
-		"title":  category.Title,
-		"skills": skills,
+// Patch changes only the fields the patch touches. PUT replaces the whole
+// category.
+func (h *SkillCategoryHandler) Patch(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
+	}
+
+	category, err := h.Repo.Get(c.UserContext(), id)
+	if err != nil {
+		return apperr.FromDB(err, "Skill category not found")
+	}
+	if err := checkIfMatch(c, category.Version); err != nil {
+		return err
+	}
+
+	req, err := applyPatch(c, CreateSkillCategoryRequest{
+		Title:    category.Title,
+		Skills:   category.Skills,
+		Position: &category.Position,
 	})
+	if err != nil {
+		return err
+	}
+
+	stored := *category
+	category.Title = req.Title
+	category.Skills = req.Skills
+	if req.Position != nil {
+		category.Position = *req.Position
+	}
+
+	// A patch that changes nothing is not a write and keeps the version
+	if reflect.DeepEqual(*category, stored) {
+		return sendRecord(c, category.Version, skillCategoryResponse(*category))
+	}
+
+	if err := h.Repo.Update(c.UserContext(), category); err != nil {
+		return fromVersionedWrite(err, "Skill category not found")
+	}
+
+	return sendRecord(c, category.Version, skillCategoryResponse(*category))
 }
 
-func DeleteSkillCategory(c *fiber.Ctx) error {
-	id := c.Params("id")
-	result := config.DB.Delete(&models.SkillCategory{}, id)
-	if result.RowsAffected == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Skill category not found",
-		})
+// Delete moves the skill category to the trash. With ?hard=true it removes the
+// skill category, live or already in the trash, for good.
+func (h *SkillCategoryHandler) Delete(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
+	}
+	hard, err := queryBool(c, "hard")
+	if err != nil {
+		return err
+	}
+
+	get, remove := h.Repo.Get, h.Repo.Delete
+	if hard {
+		get, remove = h.Repo.GetIncludingTrash, h.Repo.Purge
+	}
+
+	category, err := get(c.UserContext(), id)
+	if err != nil {
+		return apperr.FromDB(err, "Skill category not found")
+	}
+	if err := checkIfMatch(c, category.Version); err != nil {
+		return err
+	}
+
+	if err := remove(c.UserContext(), id, category.Version); err != nil {
+		return fromVersionedWrite(err, "Skill category not found")
 	}
 	return c.SendStatus(fiber.StatusNoContent)
-} 
\ No newline at end of file
+}
+
+// Restore takes the skill category out of the trash.
+func (h *SkillCategoryHandler) Restore(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
+	}
+
+	category, err := h.Repo.Restore(c.UserContext(), id)
+	if err != nil {
+		return apperr.FromDB(err, "Skill category not found in the trash")
+	}
+
+	return sendRecord(c, category.Version, skillCategoryResponse(*category))
+}
+
+// Reorder sets the display order of every skill category in one go.
+func (h *SkillCategoryHandler) Reorder(c *fiber.Ctx) error {
+	order, err := parseOrder(c)
+	if err != nil {
+		return err
+	}
+
+	categories, err := h.Repo.Reorder(c.UserContext(), order)
+	if err != nil {
+		return fromReorder(err, "skill category")
+	}
+
+	response := make([]SkillCategoryResponse, 0, len(categories))
+	for _, item := range categories {
+		response = append(response, skillCategoryResponse(item))
+	}
+	return c.JSON(fiber.Map{"data": response})
+}
+
+// ReorderSkills sets the display order of the skills within a category.
+// The body must list every skill of the category exactly once, and like a
+// PUT it needs If-Match.
+func (h *SkillCategoryHandler) ReorderSkills(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
+	}
+
+	var req OrderSkillsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperr.BadRequest("Invalid request body: " + err.Error())
+	}
+	if errs := validation.Validate(req); errs != nil {
+		return apperr.Validation(errs)
+	}
+
+	category, err := h.Repo.Get(c.UserContext(), id)
+	if err != nil {
+		return apperr.FromDB(err, "Skill category not found")
+	}
+	if err := checkIfMatch(c, category.Version); err != nil {
+		return err
+	}
+	if !sameSkills(category.Skills, req.Skills) {
+		return apperr.Conflict("skills must list every skill of the category exactly once; fetch it again and retry")
+	}
+
+	category.Skills = req.Skills
+	if err := h.Repo.Update(c.UserContext(), category); err != nil {
+		return fromVersionedWrite(err, "Skill category not found")
+	}
+
+	return sendRecord(c, category.Version, skillCategoryResponse(*category))
+}
+
+// sameSkills reports whether order holds exactly the skills in current.
+// Both are free of duplicates.
+func sameSkills(current, order []string) bool {
+	if len(current) != len(order) {
+		return false
+	}
+	listed := make(map[string]bool, len(order))
+	for _, skill := range order {
+		listed[skill] = true
+	}
+	for _, skill := range current {
+		if !listed[skill] {
+			return false
+		}
+	}
+	return true
+}
+
+func skillCategoryResponse(s models.SkillCategory) SkillCategoryResponse {
+	return SkillCategoryResponse{
+		ID:        s.ID,
+		Title:     s.Title,
+		Skills:    s.Skills,
+		Position:  s.Position,
+		Version:   s.Version,
+		DeletedAt: deletedAt(s.DeletedAt),
+	}
+}
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// deletedAt is when a record was moved to the trash, or nil for live
+// records.
+func deletedAt(d gorm.DeletedAt) *time.Time {
+	if !d.Valid {
+		return nil
+	}
+	return &d.Time
+}
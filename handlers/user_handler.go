@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"wannn-site-rebuild-api/apperr"
+	"wannn-site-rebuild-api/auth"
+	"wannn-site-rebuild-api/models"
+	"wannn-site-rebuild-api/repository"
+	"wannn-site-rebuild-api/validation"
+)
+
+type CreateUserRequest struct {
+	Email    string `json:"email" validate:"required,max=255,email"`
+	Password string `json:"password" validate:"required"`
+	Role     string `json:"role" validate:"required"`
+}
+
+type UpdateUserRequest struct {
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+type UserResponse struct {
+	ID        uint      `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserHandler serves the /users endpoints.
+type UserHandler struct {
+	Users repository.UserRepository
+}
+
+func NewUserHandler(users repository.UserRepository) *UserHandler {
+	return &UserHandler{Users: users}
+}
+
+func (h *UserHandler) List(c *fiber.Ctx) error {
+	users, err := h.Users.List(c.UserContext())
+	if err != nil {
+		return apperr.FromDB(err, "")
+	}
+
+	response := make([]UserResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, userResponse(user))
+	}
+	return c.JSON(response)
+}
+
+func (h *UserHandler) Create(c *fiber.Ctx) error {
+	var req CreateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperr.BadRequest("Invalid request body: " + err.Error())
+	}
+	if errs := validation.Validate(req); errs != nil {
+		return apperr.Validation(errs)
+	}
+
+	role, err := auth.ParseRole(req.Role)
+	if err != nil {
+		return apperr.Validation(validation.Errors{"role": err.Error()})
+	}
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		return apperr.Validation(validation.Errors{"password": err.Error()})
+	}
+
+	user := models.User{Email: normalizeEmail(req.Email), PasswordHash: hash, Role: string(role)}
+	err = h.Users.Create(c.UserContext(), &user)
+	if errors.Is(err, repository.ErrDuplicate) {
+		return apperr.Conflict("A user with this email already exists")
+	}
+	if err != nil {
+		return apperr.FromDB(err, "")
+	}
+	return c.Status(fiber.StatusCreated).JSON(userResponse(user))
+}
+
+func (h *UserHandler) Update(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.Users.Get(c.UserContext(), id)
+	if err != nil {
+		return apperr.FromDB(err, "User not found")
+	}
+
+	var req UpdateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return apperr.BadRequest("Invalid request body: " + err.Error())
+	}
+
+	if req.Password != "" {
+		hash, err := auth.HashPassword(req.Password)
+		if err != nil {
+			return apperr.Validation(validation.Errors{"password": err.Error()})
+		}
+		user.PasswordHash = hash
+	}
+
+	if req.Role != "" {
+		role, err := auth.ParseRole(req.Role)
+		if err != nil {
+			return apperr.Validation(validation.Errors{"role": err.Error()})
+		}
+		user.Role = string(role)
+	}
+
+	err = h.Users.Update(c.UserContext(), user)
+	if errors.Is(err, repository.ErrLastOwner) {
+		return apperr.Conflict("Cannot demote the last owner")
+	}
+	if err != nil {
+		return apperr.FromDB(err, "User not found")
+	}
+	return c.JSON(userResponse(*user))
+}
+
+func (h *UserHandler) Delete(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.Users.Delete(c.UserContext(), id)
+	if errors.Is(err, repository.ErrLastOwner) {
+		return apperr.Conflict("Cannot delete the last owner")
+	}
+	if err != nil {
+		return apperr.FromDB(err, "User not found")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func userResponse(user models.User) UserResponse {
+	return UserResponse{
+		ID:        user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt,
+	}
+}
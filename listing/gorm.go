@@ -0,0 +1,92 @@
+package listing
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"wannn-site-rebuild-api/models"
+)
+
+// likeEscaper escapes LIKE wildcards in user input. SQLite has no default
+// escape character, so every LIKE using it names one with ESCAPE.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Where applies the filters of q to db.
+func Where(db *gorm.DB, spec Spec, q Query) *gorm.DB {
+	for name, value := range q.Filters {
+		f := spec.Filters[name]
+		switch f.Match {
+		case Equal:
+			db = db.Where("LOWER("+f.Column+") = LOWER(?)", value)
+		case Contains:
+			db = db.Where("LOWER("+f.Column+") LIKE LOWER(?) ESCAPE '\\'", "%"+likeEscaper.Replace(value)+"%")
+		case HasElement:
+			if db.Dialector.Name() != "postgres" {
+				db = db.Where(hasElementText(f.Column), elementLiteral(value))
+				continue
+			}
+			// @> rather than = ANY() so the GIN index on the column is used
+			db = db.Where(f.Column+" @> ARRAY[?]::text[]", value)
+		}
+	}
+	return db
+}
+
+// hasElementText matches an array stored as literal text (SQLite) that
+// contains the element passed as its argument: the braces are swapped for
+// commas so every quoted element is surrounded by ",".
+func hasElementText(column string) string {
+	return "instr(',' || substr(" + column + ", 2, length(" + column + ") - 2) || ',', ?) > 0"
+}
+
+// elementLiteral quotes value the way models.StringArray writes it, between
+// commas.
+func elementLiteral(value string) string {
+	literal, _ := models.StringArray{value}.Value()
+	s := literal.(string)
+	return "," + s[1:len(s)-1] + ","
+}
+
+// Paginate applies the sort order, the cursor or offset and a limit of
+// PerPage+1 (so Finish can tell whether another page exists) to db.
+func Paginate(db *gorm.DB, spec Spec, q Query) *gorm.DB {
+	for _, s := range q.Sort {
+		f, _ := spec.field(s.Field)
+		if s.Desc {
+			db = db.Order(f.Column + " DESC")
+		} else {
+			db = db.Order(f.Column)
+		}
+	}
+
+	if q.After != nil {
+		sql, args := keyset(spec, q)
+		db = db.Where(sql, args...)
+	}
+	return db.Offset(q.Offset()).Limit(q.PerPage + 1)
+}
+
+// keyset builds the condition selecting rows after the cursor position:
+// (a > x) OR (a = x AND b < y) OR (a = x AND b = y AND id > z) ...
+// with each comparison following its field's sort direction.
+func keyset(spec Spec, q Query) (string, []interface{}) {
+	var ors []string
+	var args []interface{}
+	for i, s := range q.Sort {
+		var ands []string
+		for j := 0; j < i; j++ {
+			f, _ := spec.field(q.Sort[j].Field)
+			ands = append(ands, f.Column+" = ?")
+			args = append(args, q.After[j])
+		}
+		f, _ := spec.field(s.Field)
+		op := " > ?"
+		if s.Desc {
+			op = " < ?"
+		}
+		ands = append(ands, f.Column+op)
+		args = append(args, q.After[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
@@ -0,0 +1,290 @@
+// Package listing parses pagination, sorting and filtering query parameters
+// for list endpoints and applies them to GORM queries.
+//
+//	?page=2&per_page=20        offset pagination
+//	?after=<cursor>&per_page=20 cursor pagination, using meta.next_cursor
+//	?sort=-created_at,title     sort fields, "-" for descending
+//	?technology=React           filters declared by each resource's Spec
+package listing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"wannn-site-rebuild-api/apperr"
+)
+
+const (
+	DefaultPerPage = 20
+	MaxPerPage     = 100
+)
+
+// Kind is the type of a sortable field, used to decode cursors.
+type Kind int
+
+const (
+	Int Kind = iota
+	String
+	Time
+)
+
+// Field is a sortable field: its column and the Go struct field holding
+// its value on the model.
+type Field struct {
+	Column string
+	GoName string
+	Kind   Kind
+}
+
+// Match is how a filter compares its column with the query value.
+type Match int
+
+const (
+	// Equal matches the whole value, ignoring case.
+	Equal Match = iota
+	// Contains matches a substring, ignoring case.
+	Contains
+	// HasElement matches rows whose array column contains the value.
+	HasElement
+)
+
+// Filter is a query parameter that narrows a list.
+type Filter struct {
+	Column string
+	GoName string
+	Match  Match
+}
+
+// Spec declares what a list endpoint can be sorted and filtered by. "id" is
+// always sortable and is appended as the final tie-breaker.
+type Spec struct {
+	Sortable map[string]Field
+	Filters  map[string]Filter
+	// Default is the sort order of requests without ?sort.
+	Default []Sort
+}
+
+// Sort orders by one field.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Query is a parsed list request.
+type Query struct {
+	Page    int
+	PerPage int
+	// After holds the sort values of the last item of the previous page in
+	// cursor mode, or nil in page mode.
+	After   []interface{}
+	Sort    []Sort
+	Filters map[string]string
+}
+
+// Meta is returned alongside every list.
+type Meta struct {
+	Total      int64  `json:"total"`
+	PerPage    int    `json:"per_page"`
+	Page       int    `json:"page,omitempty"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+var idField = Field{Column: "id", GoName: "ID", Kind: Int}
+
+func (s Spec) field(name string) (Field, bool) {
+	if name == "id" {
+		return idField, true
+	}
+	f, ok := s.Sortable[name]
+	return f, ok
+}
+
+// Parse reads the list query parameters of c according to spec.
+func Parse(c *fiber.Ctx, spec Spec) (Query, error) {
+	q := Query{Page: 1, PerPage: DefaultPerPage, Filters: map[string]string{}}
+
+	if v := c.Query("per_page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxPerPage {
+			return q, apperr.BadRequest(fmt.Sprintf("per_page must be between 1 and %d", MaxPerPage))
+		}
+		q.PerPage = n
+	}
+	if v := c.Query("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return q, apperr.BadRequest("page must be a positive integer")
+		}
+		q.Page = n
+	}
+
+	hasID := false
+	if v := c.Query("sort"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			s := Sort{Field: strings.TrimSpace(part)}
+			if strings.HasPrefix(s.Field, "-") {
+				s.Field, s.Desc = s.Field[1:], true
+			}
+			if _, ok := spec.field(s.Field); !ok {
+				return q, apperr.BadRequest(fmt.Sprintf("cannot sort by %q", s.Field))
+			}
+			hasID = hasID || s.Field == "id"
+			q.Sort = append(q.Sort, s)
+		}
+	} else {
+		q.Sort = append(q.Sort, spec.Default...)
+	}
+	if !hasID {
+		q.Sort = append(q.Sort, Sort{Field: "id"})
+	}
+
+	if v := c.Query("after"); v != "" {
+		if c.Query("page") != "" {
+			return q, apperr.BadRequest("page and after cannot be combined")
+		}
+		after, err := decodeCursor(v, spec, q.Sort)
+		if err != nil {
+			return q, apperr.BadRequest("after is not a valid cursor")
+		}
+		q.After = after
+	}
+
+	for name := range spec.Filters {
+		if v := strings.TrimSpace(c.Query(name)); v != "" {
+			q.Filters[name] = v
+		}
+	}
+	return q, nil
+}
+
+// Offset is the number of items skipped in page mode.
+func (q Query) Offset() int {
+	if q.After != nil {
+		return 0
+	}
+	return (q.Page - 1) * q.PerPage
+}
+
+// Finish trims a page fetched with Limit(PerPage+1) to PerPage items,
+// builds its Meta and sets the Link header on c.
+func Finish[T any](c *fiber.Ctx, spec Spec, q Query, items []T, total int64) ([]T, Meta) {
+	meta := Meta{Total: total, PerPage: q.PerPage}
+
+	hasMore := len(items) > q.PerPage
+	if hasMore {
+		items = items[:q.PerPage]
+	}
+
+	if q.After != nil {
+		if hasMore {
+			meta.NextCursor = encodeCursor(spec, q.Sort, items[len(items)-1])
+		}
+	} else {
+		meta.Page = q.Page
+		meta.TotalPages = int((total + int64(q.PerPage) - 1) / int64(q.PerPage))
+		if hasMore {
+			// Offer a cursor too, so clients can switch to cursor mode
+			meta.NextCursor = encodeCursor(spec, q.Sort, items[len(items)-1])
+		}
+	}
+
+	setLinkHeader(c, q, meta)
+	return items, meta
+}
+
+func setLinkHeader(c *fiber.Ctx, q Query, meta Meta) {
+	var links []string
+	link := func(rel string, set map[string]string) {
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, pageURL(c, set), rel))
+	}
+
+	if q.After != nil {
+		if meta.NextCursor != "" {
+			link("next", map[string]string{"after": meta.NextCursor})
+		}
+	} else {
+		last := meta.TotalPages
+		if last < 1 {
+			last = 1
+		}
+		link("first", map[string]string{"page": "1"})
+		if q.Page > 1 {
+			link("prev", map[string]string{"page": strconv.Itoa(q.Page - 1)})
+		}
+		if q.Page < last {
+			link("next", map[string]string{"page": strconv.Itoa(q.Page + 1)})
+		}
+		link("last", map[string]string{"page": strconv.Itoa(last)})
+	}
+	c.Set(fiber.HeaderLink, strings.Join(links, ", "))
+}
+
+// pageURL is the current request URL with the pagination parameters
+// replaced by set.
+func pageURL(c *fiber.Ctx, set map[string]string) string {
+	args := fiber.AcquireArgs()
+	defer fiber.ReleaseArgs(args)
+	c.Request().URI().QueryArgs().CopyTo(args)
+	args.Del("page")
+	args.Del("after")
+	for k, v := range set {
+		args.Set(k, v)
+	}
+	return c.BaseURL() + c.Path() + "?" + args.String()
+}
+
+func encodeCursor(spec Spec, sorts []Sort, item interface{}) string {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	values := make([]interface{}, 0, len(sorts))
+	for _, s := range sorts {
+		f, _ := spec.field(s.Field)
+		values = append(values, v.FieldByName(f.GoName).Interface())
+	}
+	b, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(cursor string, spec Spec, sorts []Sort) ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	if len(raw) != len(sorts) {
+		return nil, fmt.Errorf("cursor has %d values, want %d", len(raw), len(sorts))
+	}
+
+	values := make([]interface{}, len(sorts))
+	for i, s := range sorts {
+		f, _ := spec.field(s.Field)
+		var err error
+		switch f.Kind {
+		case Int:
+			var n int64
+			err = json.Unmarshal(raw[i], &n)
+			values[i] = n
+		case String:
+			var str string
+			err = json.Unmarshal(raw[i], &str)
+			values[i] = str
+		case Time:
+			var t time.Time
+			err = json.Unmarshal(raw[i], &t)
+			values[i] = t
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,142 @@
+package listing
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Slice applies q to items held in memory the way Where and Paginate do in
+// SQL. It returns at most PerPage+1 items and the number of items matching
+// the filters.
+func Slice[T any](items []T, spec Spec, q Query) ([]T, int64) {
+	var matched []T
+	for _, item := range items {
+		if matches(item, spec, q) {
+			matched = append(matched, item)
+		}
+	}
+	total := int64(len(matched))
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return compareItem(matched[i], matched[j], spec, q.Sort) < 0
+	})
+
+	if q.After != nil {
+		start := len(matched)
+		for i, item := range matched {
+			if compareCursor(item, q.After, spec, q.Sort) > 0 {
+				start = i
+				break
+			}
+		}
+		matched = matched[start:]
+	}
+
+	offset := q.Offset()
+	if offset > len(matched) {
+		offset = len(matched)
+	}
+	matched = matched[offset:]
+	if len(matched) > q.PerPage+1 {
+		matched = matched[:q.PerPage+1]
+	}
+	return matched, total
+}
+
+func matches(item interface{}, spec Spec, q Query) bool {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	for name, value := range q.Filters {
+		f := spec.Filters[name]
+		field := v.FieldByName(f.GoName)
+		switch f.Match {
+		case Equal:
+			if !strings.EqualFold(field.String(), value) {
+				return false
+			}
+		case Contains:
+			if !strings.Contains(strings.ToLower(field.String()), strings.ToLower(value)) {
+				return false
+			}
+		case HasElement:
+			found := false
+			for i := 0; i < field.Len(); i++ {
+				if field.Index(i).String() == value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func compareItem(a, b interface{}, spec Spec, sorts []Sort) int {
+	va, vb := reflect.Indirect(reflect.ValueOf(a)), reflect.Indirect(reflect.ValueOf(b))
+	for _, s := range sorts {
+		f, _ := spec.field(s.Field)
+		c := compareValues(va.FieldByName(f.GoName).Interface(), vb.FieldByName(f.GoName).Interface())
+		if s.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareCursor orders item relative to a cursor position.
+func compareCursor(item interface{}, after []interface{}, spec Spec, sorts []Sort) int {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	for i, s := range sorts {
+		f, _ := spec.field(s.Field)
+		c := compareValues(v.FieldByName(f.GoName).Interface(), after[i])
+		if s.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareValues(a, b interface{}) int {
+	switch x := a.(type) {
+	case string:
+		return strings.Compare(x, b.(string))
+	case time.Time:
+		y := b.(time.Time)
+		switch {
+		case x.Before(y):
+			return -1
+		case x.After(y):
+			return 1
+		}
+		return 0
+	default:
+		x64, y64 := toInt64(a), toInt64(b)
+		switch {
+		case x64 < y64:
+			return -1
+		case x64 > y64:
+			return 1
+		}
+		return 0
+	}
+}
+
+func toInt64(v interface{}) int64 {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	default:
+		return rv.Int()
+	}
+}
@@ -1,75 +1,111 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/joho/godotenv"
 	"wannn-site-rebuild-api/config"
-	"wannn-site-rebuild-api/handlers"
+	"wannn-site-rebuild-api/logging"
+	"wannn-site-rebuild-api/ratelimit"
+	"wannn-site-rebuild-api/server"
+	"wannn-site-rebuild-api/tracing"
 )
 
 func main() {
-	// Load environment variables
+	// Load environment variables. The file is optional, so offline runs and
+	// CI can configure everything through the environment
 	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
+		log.Println("Warning: Error loading .env file, will use system environment variables")
 	}
 
-	// Initialize database connection and run migrations
-	config.InitDatabase()
+	// CLI subcommands (migrate, ...) run instead of the server; anything
+	// else on the command line is a server flag
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		if !runCommand(os.Args[1], os.Args[2:]) {
+			log.Fatalf("Unknown command %q\n\n%s", os.Args[1], usage)
+		}
+		return
+	}
 
-	// Create Fiber app
-	app := fiber.New()
+	cfg, err := server.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Println(usage)
+		return
+	}
+	if err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
 
-	// Middleware
-	app.Use(logger.New())
-	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept",
-		AllowMethods: "GET,POST,PUT,DELETE",
-	}))
+	// From here on everything, including the standard log package, logs
+	// structured records at the configured level
+	logger, err := logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+	slog.SetDefault(logger)
 
-	// Routes
-	api := app.Group("/")
+	// Traces are exported in the background; flush what is buffered on exit
+	flushTraces, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
 
-	// Home
-	api.Get("/", func(c *fiber.Ctx) error {
-		return c.SendString("Welcome to wandhx.site Backend API!")
-	})
-	
-	// Experience routes
-	experiences := api.Group("experiences")
-	experiences.Get("/", handlers.GetExperiences)
-	experiences.Get("/:id", handlers.GetExperienceByID)
-	experiences.Post("/", handlers.CreateExperience)
-	experiences.Put("/:id", handlers.UpdateExperience)
-	experiences.Delete("/:id", handlers.DeleteExperience)
+	// Initialize the storage backend and run migrations
+	config.InitDatabase(cfg.Database)
 
-	// Project routes
-	projects := api.Group("projects")
-	projects.Get("/", handlers.GetProjects)
-	projects.Get("/:id", handlers.GetProjectByID)
-	projects.Post("/", handlers.CreateProject)
-	projects.Put("/:id", handlers.UpdateProject)
-	projects.Delete("/:id", handlers.DeleteProject)
+	// Rate limit counters; with Redis every instance shares one budget
+	rateLimitStore, err := ratelimit.NewStore(cfg.RateLimit)
+	if err != nil {
+		logging.Fatal("Failed to open the rate limit store", "error", err)
+	}
+	if redisStore, ok := rateLimitStore.(*ratelimit.RedisStore); ok {
+		if err := redisStore.Ping(context.Background()); err != nil {
+			slog.Warn("Rate limit store is unreachable, requests are not limited until it is back", "error", err)
+		}
+	}
+
+	app := server.New(cfg, server.Deps{
+		Repos:          config.Repos,
+		DB:             config.DB,
+		Tokens:         config.LoadTokens(),
+		RateLimitStore: rateLimitStore,
+	})
 
-	// Skill Category routes
-	skills := api.Group("skills")
-	skills.Get("/", handlers.GetSkillCategories)
-	skills.Get("/:id", handlers.GetSkillCategoryByID)
-	skills.Post("/", handlers.CreateSkillCategory)
-	skills.Put("/:id", handlers.UpdateSkillCategory)
-	skills.Delete("/:id", handlers.DeleteSkillCategory)
+	// Serve until SIGINT or SIGTERM, then drain in-flight requests
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// Get port from env
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "3000"
+	// Deleted content past its retention is purged in the background. The
+	// purge is stopped and waited for before the database is closed, also
+	// when the server fails without a signal.
+	purgeCtx, stopPurge := context.WithCancel(ctx)
+	purged := make(chan struct{})
+	go func() {
+		defer close(purged)
+		server.PurgeTrash(purgeCtx, config.Repos, cfg.TrashRetention)
+	}()
+	err = server.Run(ctx, app, cfg)
+	stopPurge()
+	<-purged
+	config.CloseDatabase()
+	rateLimitStore.Close()
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := flushTraces(flushCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
 	}
-
-	// Start server
-	log.Fatal(app.Listen(":" + port))
-} 
\ No newline at end of file
+	cancel()
+	if err != nil {
+		logging.Fatal("Server stopped with an error", "error", err)
+	}
+	slog.Info("Server stopped")
+}
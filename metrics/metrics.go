@@ -0,0 +1,170 @@
+// Package metrics records HTTP traffic and renders it, along with any
+// other samples the caller adds, in the Prometheus text exposition format.
+package metrics
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"wannn-site-rebuild-api/apperr"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request duration
+// histogram. They match the Prometheus client defaults.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// unmatchedRoute labels requests no route matched, so probing random paths
+// cannot create a series per path.
+const unmatchedRoute = "unmatched"
+
+type requestKey struct {
+	method, route string
+	status        int
+}
+
+type routeKey struct {
+	method, route string
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// HTTP collects request counts, latencies and the number of requests in
+// flight. The zero value is not usable; create one with New.
+type HTTP struct {
+	inFlight atomic.Int64
+
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	durations map[routeKey]*histogram
+}
+
+func New() *HTTP {
+	return &HTTP{
+		requests:  map[requestKey]uint64{},
+		durations: map[routeKey]*histogram{},
+	}
+}
+
+// Middleware records every request that passes through it. It labels
+// requests with the route pattern (/projects/:id), never the raw path. It
+// runs after the logger, which renders handler errors, so the status is
+// taken from the error the handler returned.
+func (m *HTTP) Middleware(c *fiber.Ctx) error {
+	m.inFlight.Add(1)
+	defer m.inFlight.Add(-1)
+
+	start := time.Now()
+	err := c.Next()
+	elapsed := time.Since(start)
+
+	status := c.Response().StatusCode()
+	route := c.Route().Path
+	if err != nil {
+		status = fiber.StatusInternalServerError
+		var ae *apperr.Error
+		var fe *fiber.Error
+		switch {
+		case errors.As(err, &ae):
+			status = ae.Status
+		case errors.As(err, &fe):
+			status = fe.Code
+			// Fiber's own 404 and 405 mean no route matched
+			if fe.Code == fiber.StatusNotFound || fe.Code == fiber.StatusMethodNotAllowed {
+				route = unmatchedRoute
+			}
+		}
+	}
+	// Fiber reuses the request buffers, so copy the method before keeping it
+	m.observe(utils.CopyString(c.Method()), route, status, elapsed)
+	return err
+}
+
+func (m *HTTP) observe(method, route string, status int, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{method, route, status}]++
+
+	key := routeKey{method, route}
+	h := m.durations[key]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		m.durations[key] = h
+	}
+	seconds := elapsed.Seconds()
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// WriteTo renders the HTTP metrics to w.
+func (m *HTTP) WriteTo(w *Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Help("wandhx_http_requests_in_flight", "gauge", "Requests currently being served.")
+	w.Sample("wandhx_http_requests_in_flight", nil, float64(m.inFlight.Load()))
+
+	requestKeys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		requestKeys = append(requestKeys, k)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		a, b := requestKeys[i], requestKeys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	w.Help("wandhx_http_requests_total", "counter", "Requests served, by method, route and status.")
+	for _, k := range requestKeys {
+		w.Sample("wandhx_http_requests_total",
+			Labels{"method", k.method, "route", k.route, "status", strconv.Itoa(k.status)},
+			float64(m.requests[k]))
+	}
+
+	routeKeys := make([]routeKey, 0, len(m.durations))
+	for k := range m.durations {
+		routeKeys = append(routeKeys, k)
+	}
+	sort.Slice(routeKeys, func(i, j int) bool {
+		a, b := routeKeys[i], routeKeys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		return a.method < b.method
+	})
+	w.Help("wandhx_http_request_duration_seconds", "histogram", "Request latency, by method and route.")
+	for _, k := range routeKeys {
+		h := m.durations[k]
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			cumulative += h.counts[i]
+			w.Sample("wandhx_http_request_duration_seconds_bucket",
+				Labels{"method", k.method, "route", k.route, "le", formatFloat(bound)},
+				float64(cumulative))
+		}
+		w.Sample("wandhx_http_request_duration_seconds_bucket",
+			Labels{"method", k.method, "route", k.route, "le", "+Inf"}, float64(h.count))
+		w.Sample("wandhx_http_request_duration_seconds_sum", Labels{"method", k.method, "route", k.route}, h.sum)
+		w.Sample("wandhx_http_request_duration_seconds_count", Labels{"method", k.method, "route", k.route}, float64(h.count))
+	}
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the media type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Labels are alternating label names and values.
+type Labels []string
+
+// Writer builds a Prometheus text format document.
+type Writer struct {
+	b strings.Builder
+}
+
+// Help starts a metric family with its HELP and TYPE lines.
+func (w *Writer) Help(name, typ, help string) {
+	w.b.WriteString("# HELP " + name + " " + help + "\n")
+	w.b.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// Sample writes one sample line.
+func (w *Writer) Sample(name string, labels Labels, value float64) {
+	w.b.WriteString(name)
+	if len(labels) > 0 {
+		w.b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.b.WriteByte(',')
+			}
+			w.b.WriteString(labels[i] + `="` + labelEscaper.Replace(labels[i+1]) + `"`)
+		}
+		w.b.WriteByte('}')
+	}
+	w.b.WriteByte(' ')
+	w.b.WriteString(formatFloat(value))
+	w.b.WriteByte('\n')
+}
+
+// String returns the document written so far.
+func (w *Writer) String() string {
+	return w.b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
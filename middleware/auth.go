@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"wannn-site-rebuild-api/apperr"
+	"wannn-site-rebuild-api/auth"
+	"wannn-site-rebuild-api/logging"
+	"wannn-site-rebuild-api/repository"
+)
+
+// principalKey is the fiber.Ctx local holding the authenticated Principal.
+const principalKey = "principal"
+
+// lastUsedResolution is how stale an API key's last use may get before a
+// request records it again, so reads don't each cost a database write.
+const lastUsedResolution = time.Minute
+
+// RequireAuth rejects requests without a valid API key or JWT. Credentials
+// are read from "Authorization: Bearer <token>" or the X-API-Key header.
+// API keys are looked up in keys; tokens may be nil when JWT authentication
+// is disabled.
+func RequireAuth(keys repository.APIKeyRepository, tokens *auth.Tokens) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Identify may already have authenticated the caller
+		if CurrentPrincipal(c) != nil {
+			return c.Next()
+		}
+		principal, err := authenticate(c, keys, tokens)
+		if errors.Is(err, auth.ErrMissingCredentials) || errors.Is(err, auth.ErrInvalidCredentials) {
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="wandhx-be"`)
+			return apperr.Unauthorized("A valid API key or bearer token is required")
+		}
+		if err != nil {
+			return apperr.FromDB(err, "")
+		}
+		c.Locals(principalKey, principal)
+		return c.Next()
+	}
+}
+
+// Identify authenticates the caller when the request carries a valid
+// credential and lets every request through, so routes that do not require
+// authentication can still tell clients apart. RequireAuth later rejects
+// requests whose credentials were missing or invalid.
+func Identify(keys repository.APIKeyRepository, tokens *auth.Tokens) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if principal, err := authenticate(c, keys, tokens); err == nil {
+			c.Locals(principalKey, principal)
+		}
+		return c.Next()
+	}
+}
+
+// RequirePermission rejects principals whose role does not grant perm. It
+// must run after RequireAuth.
+func RequirePermission(perm auth.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !CurrentPrincipal(c).Can(perm) {
+			return apperr.Forbidden("Your role does not grant " + string(perm))
+		}
+		return c.Next()
+	}
+}
+
+// CurrentPrincipal returns the caller authenticated by RequireAuth, or nil.
+func CurrentPrincipal(c *fiber.Ctx) *auth.Principal {
+	p, _ := c.Locals(principalKey).(*auth.Principal)
+	return p
+}
+
+func authenticate(c *fiber.Ctx, keys repository.APIKeyRepository, tokens *auth.Tokens) (*auth.Principal, error) {
+	token := c.Get("X-API-Key")
+	if token == "" {
+		header := c.Get(fiber.HeaderAuthorization)
+		if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+			token = strings.TrimSpace(header[7:])
+		}
+	}
+	if token == "" {
+		return nil, auth.ErrMissingCredentials
+	}
+
+	if auth.IsAPIKey(token) {
+		return authenticateAPIKey(c, keys, token)
+	}
+	if tokens == nil {
+		return nil, auth.ErrInvalidCredentials
+	}
+	claims, err := tokens.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.Principal{Subject: claims.Subject, Method: auth.MethodJWT, Role: claims.Role}, nil
+}
+
+func authenticateAPIKey(c *fiber.Ctx, keys repository.APIKeyRepository, token string) (*auth.Principal, error) {
+	key, err := keys.FindActive(c.UserContext(), auth.HashAPIKey(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, auth.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		if err := keys.MarkUsed(c.UserContext(), key.ID, now); err != nil {
+			logging.FromContext(c.UserContext()).Warn("Recording API key use failed", "key", key.Prefix, "error", err)
+		}
+	}
+	return &auth.Principal{Subject: "api_key:" + key.Prefix, Method: auth.MethodAPIKey, Role: auth.Role(key.Role)}, nil
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"wannn-site-rebuild-api/apperr"
+	"wannn-site-rebuild-api/auth"
+	"wannn-site-rebuild-api/models"
+	"wannn-site-rebuild-api/repository"
+)
+
+func TestRequireAuth(t *testing.T) {
+	ctx := context.Background()
+	keys := repository.NewMemory().APIKeys
+	newKey := func() (string, *models.APIKey) {
+		key, prefix, hash, err := auth.GenerateAPIKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		record := &models.APIKey{Name: "test", Prefix: prefix, KeyHash: hash, Role: string(auth.RoleEditor)}
+		if err := keys.Create(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+		return key, record
+	}
+	activeKey, _ := newKey()
+	revokedKey, revoked := newKey()
+	if err := keys.Revoke(ctx, revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := auth.NewTokens(auth.TokenConfig{Algorithm: auth.HS256, Secret: []byte("0123456789abcdef0123456789abcdef"), Issuer: "wandhx-be"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwt, err := tokens.Sign("user:1", auth.RoleOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newApp := func(tokens *auth.Tokens) *fiber.App {
+		app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+		app.Post("/", RequireAuth(keys, tokens), func(c *fiber.Ctx) error {
+			p := CurrentPrincipal(c)
+			return c.SendString(p.Method + " " + string(p.Role))
+		})
+		return app
+	}
+	withJWT, withoutJWT := newApp(tokens), newApp(nil)
+
+	tests := []struct {
+		name       string
+		app        *fiber.App
+		header     string
+		value      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"API key header", withJWT, "X-API-Key", activeKey, http.StatusOK, "api_key editor"},
+		{"API key as bearer", withJWT, "Authorization", "Bearer " + activeKey, http.StatusOK, "api_key editor"},
+		{"lower-case scheme", withJWT, "Authorization", "bearer " + activeKey, http.StatusOK, "api_key editor"},
+		{"JWT", withJWT, "Authorization", "Bearer " + jwt, http.StatusOK, "jwt owner"},
+		{"no credentials", withJWT, "", "", http.StatusUnauthorized, ""},
+		{"revoked API key", withJWT, "X-API-Key", revokedKey, http.StatusUnauthorized, ""},
+		{"unknown API key", withJWT, "X-API-Key", activeKey + "x", http.StatusUnauthorized, ""},
+		{"basic scheme", withJWT, "Authorization", "Basic " + activeKey, http.StatusUnauthorized, ""},
+		{"scheme only", withJWT, "Authorization", "Bearer", http.StatusUnauthorized, ""},
+		{"empty bearer", withJWT, "Authorization", "Bearer    ", http.StatusUnauthorized, ""},
+		{"no space after scheme", withJWT, "Authorization", "Bearer" + jwt, http.StatusUnauthorized, ""},
+		{"garbage bearer", withJWT, "Authorization", "Bearer not-a-token", http.StatusUnauthorized, ""},
+		{"JWT with JWT disabled", withoutJWT, "Authorization", "Bearer " + jwt, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			resp, err := tt.app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+			if tt.wantBody != "" {
+				body := make([]byte, 64)
+				n, _ := resp.Body.Read(body)
+				if got := string(body[:n]); got != tt.wantBody {
+					t.Errorf("principal %q, want %q", got, tt.wantBody)
+				}
+			}
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		if role := c.Get("X-Role"); role != "" {
+			c.Locals(principalKey, &auth.Principal{Subject: "test", Method: auth.MethodJWT, Role: auth.Role(role)})
+		}
+		return c.Next()
+	})
+	app.Delete("/", RequirePermission(auth.PermContentDelete), func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusNoContent)
+	})
+
+	for role, want := range map[string]int{
+		"owner":  http.StatusNoContent,
+		"editor": http.StatusForbidden,
+		"viewer": http.StatusForbidden,
+		"":       http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		req.Header.Set("X-Role", role)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != want {
+			t.Errorf("role %q: status %d, want %d", role, resp.StatusCode, want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+// CORSConfig describes which browser origins may call the API. Public reads
+// and writes have separate policies, so a preview deployment can read
+// content without being able to change it.
+type CORSConfig struct {
+	// Origins may call GET and HEAD routes. Entries are exact origins
+	// (https://wandhx.site), wildcard subdomains (https://*.vercel.app) or
+	// * for any origin.
+	Origins []string
+	// WriteOrigins may call WriteMethods. They take the same forms as
+	// Origins except *, and are usually a short list of admin frontends.
+	// When empty, browsers cannot write cross-origin at all.
+	WriteOrigins []string
+	WriteMethods []string
+	// Headers are the request headers browsers may send.
+	Headers []string
+	// AllowCredentials lets write origins send cookies and client
+	// certificates. Public reads never need them.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight answer.
+	MaxAge time.Duration
+}
+
+// exposedHeaders are the response headers scripts may read.
+var exposedHeaders = []string{
+	"X-Request-ID", fiber.HeaderETag,
+	"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", fiber.HeaderRetryAfter,
+}
+
+// CORS applies the read policy to GET and HEAD requests and the write
+// policy to everything else. Preflight requests are judged by the method
+// they ask about.
+func CORS(cfg CORSConfig) fiber.Handler {
+	common := cors.Config{
+		AllowHeaders:  strings.Join(cfg.Headers, ","),
+		ExposeHeaders: strings.Join(exposedHeaders, ","),
+		MaxAge:        int(cfg.MaxAge.Seconds()),
+	}
+
+	reads := common
+	reads.AllowOrigins = strings.Join(cfg.Origins, ",")
+	reads.AllowMethods = fiber.MethodGet + "," + fiber.MethodHead
+
+	writes := common
+	writes.AllowMethods = strings.Join(cfg.WriteMethods, ",")
+	writes.AllowCredentials = cfg.AllowCredentials
+	if len(cfg.WriteOrigins) > 0 {
+		writes.AllowOrigins = strings.Join(cfg.WriteOrigins, ",")
+	} else {
+		// An empty AllowOrigins would mean every origin
+		writes.AllowOriginsFunc = func(string) bool { return false }
+	}
+
+	readPolicy, writePolicy := cors.New(reads), cors.New(writes)
+	return func(c *fiber.Ctx) error {
+		if isCORSWrite(c) {
+			return writePolicy(c)
+		}
+		return readPolicy(c)
+	}
+}
+
+// isCORSWrite reports whether the request, or the request a preflight asks
+// about, falls under the write policy.
+func isCORSWrite(c *fiber.Ctx) bool {
+	method := c.Method()
+	if method == fiber.MethodOptions {
+		method = c.Get(fiber.HeaderAccessControlRequestMethod)
+		if method == "" {
+			return false
+		}
+	}
+	return method != fiber.MethodGet && method != fiber.MethodHead
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/trace"
+	"wannn-site-rebuild-api/apperr"
+	"wannn-site-rebuild-api/logging"
+)
+
+// RequestLogger logs one structured line per request with its request ID,
+// route, status, latency and the authenticated caller. Handlers and
+// repositories reach the same request-tagged logger through
+// logging.FromContext(c.UserContext()). It must run after the requestid
+// middleware.
+//
+// Handler errors are rendered here with apperr.Render, so the logged status
+// is the one the client receives. Middleware running before the logger
+// reads the error back with apperr.Rendered.
+func RequestLogger(c *fiber.Ctx) error {
+	start := time.Now()
+	logger := slog.Default().With("request_id", apperr.RequestID(c))
+	c.SetUserContext(logging.WithLogger(c.UserContext(), logger))
+
+	chainErr := c.Next()
+	if chainErr != nil {
+		apperr.Render(c, chainErr)
+	}
+
+	// Fiber's own 404 and 405 mean no route matched, so there is no route
+	// pattern to report
+	route := c.Route().Path
+	var fe *fiber.Error
+	if errors.As(chainErr, &fe) && (fe.Code == fiber.StatusNotFound || fe.Code == fiber.StatusMethodNotAllowed) {
+		route = ""
+	}
+
+	status := c.Response().StatusCode()
+	attrs := []any{
+		"method", c.Method(),
+		"path", c.Path(),
+		"route", route,
+		"status", status,
+		"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+		"ip", c.IP(),
+	}
+	if p := CurrentPrincipal(c); p != nil {
+		attrs = append(attrs, "user", p.Subject, "auth_method", p.Method)
+	}
+	if sc := trace.SpanContextFromContext(c.UserContext()); sc.IsValid() {
+		attrs = append(attrs, "trace_id", sc.TraceID().String())
+	}
+	if chainErr != nil {
+		attrs = append(attrs, "error", chainErr.Error())
+	}
+
+	level := slog.LevelInfo
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	logger.Log(c.UserContext(), level, "request", attrs...)
+	return nil
+}
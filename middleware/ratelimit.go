@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"wannn-site-rebuild-api/apperr"
+	"wannn-site-rebuild-api/logging"
+	"wannn-site-rebuild-api/ratelimit"
+)
+
+// RateLimit counts each request against the caller's read budget (GET and
+// HEAD) or write budget (everything else) and rejects it with 429 once the
+// budget is spent. Callers identified by Identify are counted per API key
+// or user; everyone else per client IP. The RateLimit-* headers of the
+// IETF draft tell clients where they stand.
+//
+// When the store fails, requests are let through rather than turning a
+// Redis outage into an API outage.
+func RateLimit(limiter *ratelimit.Limiter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Subjects are already namespaced (user:12, api_key:wx_...)
+		client := "ip:" + c.IP()
+		if p := CurrentPrincipal(c); p != nil {
+			client = p.Subject
+		}
+		write := c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead
+
+		d, limited, err := limiter.Allow(c.UserContext(), client, write)
+		if err != nil {
+			logging.FromContext(c.UserContext()).Warn("Rate limit store failed, allowing request", "error", err)
+			return c.Next()
+		}
+		if !limited {
+			return c.Next()
+		}
+
+		reset := strconv.Itoa(int(math.Ceil(d.Reset.Seconds())))
+		c.Set("RateLimit-Limit", strconv.FormatInt(d.Limit, 10))
+		c.Set("RateLimit-Remaining", strconv.FormatInt(d.Remaining, 10))
+		c.Set("RateLimit-Reset", reset)
+		c.Set("RateLimit-Policy", strconv.FormatInt(d.Limit, 10)+";w="+strconv.Itoa(int(d.Window.Seconds())))
+		if !d.Allowed {
+			c.Set(fiber.HeaderRetryAfter, reset)
+			return apperr.TooManyRequests("Rate limit exceeded, retry in " + reset + " seconds")
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// The initial schema mirrors what GORM's AutoMigrate used to create, and
+// uses IF NOT EXISTS so databases created that way adopt it unchanged.
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS experiences (
+					id bigserial PRIMARY KEY,
+					created_at timestamptz,
+					updated_at timestamptz,
+					deleted_at timestamptz,
+					title varchar(255) NOT NULL,
+					company varchar(255) NOT NULL,
+					period varchar(100) NOT NULL,
+					description text NOT NULL
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_experiences_deleted_at ON experiences (deleted_at)`,
+				`CREATE TABLE IF NOT EXISTS projects (
+					id bigserial PRIMARY KEY,
+					created_at timestamptz,
+					updated_at timestamptz,
+					deleted_at timestamptz,
+					title varchar(255) NOT NULL,
+					description text NOT NULL,
+					technologies text NOT NULL,
+					link varchar(255)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_projects_deleted_at ON projects (deleted_at)`,
+				`CREATE TABLE IF NOT EXISTS skill_categories (
+					id bigserial PRIMARY KEY,
+					created_at timestamptz,
+					updated_at timestamptz,
+					deleted_at timestamptz,
+					title varchar(255) NOT NULL,
+					skills text NOT NULL
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_skill_categories_deleted_at ON skill_categories (deleted_at)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP TABLE IF EXISTS skill_categories`,
+				`DROP TABLE IF EXISTS projects`,
+				`DROP TABLE IF EXISTS experiences`,
+			)
+		},
+	})
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "api_keys",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE api_keys (
+					id bigserial PRIMARY KEY,
+					created_at timestamptz,
+					updated_at timestamptz,
+					deleted_at timestamptz,
+					name varchar(255) NOT NULL,
+					prefix varchar(16) NOT NULL,
+					key_hash char(64) NOT NULL,
+					last_used_at timestamptz,
+					revoked_at timestamptz
+				)`,
+				`CREATE UNIQUE INDEX idx_api_keys_key_hash ON api_keys (key_hash)`,
+				`CREATE INDEX idx_api_keys_deleted_at ON api_keys (deleted_at)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx, `DROP TABLE IF EXISTS api_keys`)
+		},
+	})
+}
@@ -0,0 +1,46 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "users",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE users (
+					id bigserial PRIMARY KEY,
+					created_at timestamptz,
+					updated_at timestamptz,
+					deleted_at timestamptz,
+					email varchar(255) NOT NULL,
+					password_hash varchar(255) NOT NULL
+				)`,
+				`CREATE UNIQUE INDEX idx_users_email ON users (email)`,
+				`CREATE INDEX idx_users_deleted_at ON users (deleted_at)`,
+				`CREATE TABLE refresh_tokens (
+					id bigserial PRIMARY KEY,
+					created_at timestamptz,
+					updated_at timestamptz,
+					deleted_at timestamptz,
+					user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+					family_id varchar(36) NOT NULL,
+					token_hash char(64) NOT NULL,
+					expires_at timestamptz NOT NULL,
+					used_at timestamptz,
+					revoked_at timestamptz
+				)`,
+				`CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash)`,
+				`CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id)`,
+				`CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id)`,
+				`CREATE INDEX idx_refresh_tokens_deleted_at ON refresh_tokens (deleted_at)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP TABLE IF EXISTS refresh_tokens`,
+				`DROP TABLE IF EXISTS users`,
+			)
+		},
+	})
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Accounts and keys that existed before roles had full access, so they are
+// backfilled as owners; new rows get the least privileged sensible default.
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "roles",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`ALTER TABLE users ADD COLUMN role varchar(20) NOT NULL DEFAULT 'owner'`,
+				`ALTER TABLE users ALTER COLUMN role SET DEFAULT 'viewer'`,
+				`ALTER TABLE api_keys ADD COLUMN role varchar(20) NOT NULL DEFAULT 'owner'`,
+				`ALTER TABLE api_keys ALTER COLUMN role SET DEFAULT 'editor'`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`ALTER TABLE api_keys DROP COLUMN role`,
+				`ALTER TABLE users DROP COLUMN role`,
+			)
+		},
+	})
+}
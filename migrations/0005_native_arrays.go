@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// arrayColumns are the JSON-in-text columns converted to text[].
+var arrayColumns = []struct{ table, column string }{
+	{"experiences", "description"},
+	{"projects", "technologies"},
+	{"skill_categories", "skills"},
+}
+
+// Converts the JSON arrays stored in text columns to native text[] columns.
+// Postgres does not allow subqueries in ALTER COLUMN ... USING, so each
+// column is rebuilt through a temporary column. Values that are not JSON
+// arrays (such as "null") become empty arrays.
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "native_arrays",
+		Up: func(tx *gorm.DB) error {
+			for _, c := range arrayColumns {
+				err := execAll(tx,
+					fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s_arr text[]`, c.table, c.column),
+					fmt.Sprintf(`UPDATE %[1]s SET %[2]s_arr = CASE
+						WHEN jsonb_typeof(%[2]s::jsonb) = 'array'
+						THEN ARRAY(SELECT jsonb_array_elements_text(%[2]s::jsonb))
+						ELSE '{}'::text[]
+					END`, c.table, c.column),
+					fmt.Sprintf(`ALTER TABLE %s DROP COLUMN %s`, c.table, c.column),
+					fmt.Sprintf(`ALTER TABLE %[1]s RENAME COLUMN %[2]s_arr TO %[2]s`, c.table, c.column),
+					fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN %s SET NOT NULL`, c.table, c.column),
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return execAll(tx,
+				`CREATE INDEX idx_projects_technologies ON projects USING GIN (technologies)`,
+				`CREATE INDEX idx_skill_categories_skills ON skill_categories USING GIN (skills)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			err := execAll(tx,
+				`DROP INDEX IF EXISTS idx_projects_technologies`,
+				`DROP INDEX IF EXISTS idx_skill_categories_skills`,
+			)
+			if err != nil {
+				return err
+			}
+			for _, c := range arrayColumns {
+				err := tx.Exec(fmt.Sprintf(`ALTER TABLE %[1]s ALTER COLUMN %[2]s TYPE text USING to_jsonb(%[2]s)::text`, c.table, c.column)).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Adds a weighted, generated tsvector column with a GIN index to each
+// content table. array_to_string is only STABLE, so an IMMUTABLE wrapper is
+// needed to use it in a generated column.
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "full_text_search",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE OR REPLACE FUNCTION search_array_text(text[]) RETURNS text
+					LANGUAGE sql IMMUTABLE PARALLEL SAFE
+					AS $$ SELECT coalesce(array_to_string($1, ' '), '') $$`,
+				`ALTER TABLE projects ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+					setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+					setweight(to_tsvector('english', search_array_text(technologies)), 'B') ||
+					setweight(to_tsvector('english', coalesce(description, '')), 'C')
+				) STORED`,
+				`CREATE INDEX idx_projects_search_vector ON projects USING GIN (search_vector)`,
+				`ALTER TABLE experiences ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+					setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+					setweight(to_tsvector('english', coalesce(company, '')), 'A') ||
+					setweight(to_tsvector('english', search_array_text(description)), 'C')
+				) STORED`,
+				`CREATE INDEX idx_experiences_search_vector ON experiences USING GIN (search_vector)`,
+				`ALTER TABLE skill_categories ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+					setweight(to_tsvector('english', search_array_text(skills)), 'A') ||
+					setweight(to_tsvector('english', coalesce(title, '')), 'B')
+				) STORED`,
+				`CREATE INDEX idx_skill_categories_search_vector ON skill_categories USING GIN (search_vector)`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`ALTER TABLE skill_categories DROP COLUMN IF EXISTS search_vector`,
+				`ALTER TABLE experiences DROP COLUMN IF EXISTS search_vector`,
+				`ALTER TABLE projects DROP COLUMN IF EXISTS search_vector`,
+				`DROP FUNCTION IF EXISTS search_array_text(text[])`,
+			)
+		},
+	})
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// contentTables are the tables whose rows carry an optimistic concurrency
+// version.
+var contentTables = []string{"experiences", "projects", "skill_categories"}
+
+// Adds a version to every content row. Updates only succeed against the
+// version they read and bump it, and the API exposes it as the ETag.
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "record_versions",
+		Up: func(tx *gorm.DB) error {
+			for _, table := range contentTables {
+				if err := execAll(tx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN version bigint NOT NULL DEFAULT 1`, table)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, table := range contentTables {
+				if err := execAll(tx, fmt.Sprintf(`ALTER TABLE %s DROP COLUMN version`, table)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
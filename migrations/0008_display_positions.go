@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Adds an explicit display position to every content row. Existing rows
+// are numbered in id order, the order lists returned before.
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "display_positions",
+		Up: func(tx *gorm.DB) error {
+			for _, table := range contentTables {
+				err := execAll(tx,
+					fmt.Sprintf(`ALTER TABLE %s ADD COLUMN position integer NOT NULL DEFAULT 0`, table),
+					fmt.Sprintf(`UPDATE %[1]s SET position = (SELECT COUNT(*) FROM %[1]s AS earlier WHERE earlier.id < %[1]s.id)`, table),
+					fmt.Sprintf(`CREATE INDEX idx_%[1]s_position ON %[1]s (position, id)`, table),
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, table := range contentTables {
+				err := execAll(tx,
+					fmt.Sprintf(`DROP INDEX IF EXISTS idx_%s_position`, table),
+					fmt.Sprintf(`ALTER TABLE %s DROP COLUMN position`, table),
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
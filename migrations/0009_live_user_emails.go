@@ -0,0 +1,27 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Users are soft-deleted, so the unique email index only covers live
+// accounts; otherwise a deleted account's email could never be used again.
+// Both Postgres and SQLite support partial indexes, so it needs no SQLite
+// variant.
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "live_user_emails",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP INDEX IF EXISTS idx_users_email`,
+				`CREATE UNIQUE INDEX idx_users_email ON users (email) WHERE deleted_at IS NULL`,
+			)
+		},
+		// Fails while a deleted and a live account share an email
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`DROP INDEX IF EXISTS idx_users_email`,
+				`CREATE UNIQUE INDEX idx_users_email ON users (email)`,
+			)
+		},
+	})
+}
@@ -0,0 +1,254 @@
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lockKey is the Postgres advisory lock held while migrations run, so two
+// instances booting at the same time never apply the same migration twice.
+const lockKey int64 = 7_310_427_118
+
+// Migration is a single numbered schema change with its rollback.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// Status describes whether a known migration has been applied.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// schemaMigration is a row of the schema_migrations bookkeeping table.
+type schemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+var registry []Migration
+
+// register adds a migration to the registry. It is called from the init
+// function of each numbered migration file.
+func register(m Migration) {
+	registry = append(registry, m)
+}
+
+// All returns every known migration ordered by version.
+func All() []Migration {
+	all := make([]Migration, len(registry))
+	copy(all, registry)
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+// Up applies every pending migration in version order and returns the ones
+// that were applied.
+func Up(db *gorm.DB) ([]Migration, error) {
+	var applied []Migration
+	err := withLock(db, func(conn *gorm.DB) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range All() {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			if err := apply(conn, m); err != nil {
+				return err
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the most recently applied migrations, at most steps of
+// them, and returns the ones that were rolled back.
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := withLock(db, func(conn *gorm.DB) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		all := All()
+		for i := len(all) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := all[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			if err := revert(conn, m); err != nil {
+				return err
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// List reports the applied state of every known migration.
+func List(db *gorm.DB) ([]Status, error) {
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	done, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, m := range All() {
+		s := Status{Version: m.Version, Name: m.Name}
+		if row, ok := done[m.Version]; ok {
+			appliedAt := row.AppliedAt
+			s.Applied = true
+			s.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// Pending returns the known migrations that have not been applied yet,
+// without creating the bookkeeping table. A database that was never
+// migrated has every migration pending.
+func Pending(db *gorm.DB) ([]Migration, error) {
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return All(), nil
+	}
+	done, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range All() {
+		if _, ok := done[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+func apply(conn *gorm.DB, m Migration) error {
+	m = forDialect(conn, m)
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if err := m.Up(tx); err != nil {
+			return fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+		}
+		row := schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}
+		if err := tx.Create(&row).Error; err != nil {
+			return fmt.Errorf("recording migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		return nil
+	})
+}
+
+func revert(conn *gorm.DB, m Migration) error {
+	m = forDialect(conn, m)
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if m.Down == nil {
+			return fmt.Errorf("migration %d_%s cannot be rolled back", m.Version, m.Name)
+		}
+		if err := m.Down(tx); err != nil {
+			return fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+		}
+		if err := tx.Delete(&schemaMigration{}, m.Version).Error; err != nil {
+			return fmt.Errorf("unrecording migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		return nil
+	})
+}
+
+// withLock runs fn on a single pooled connection while holding the
+// migration advisory lock. Advisory locks are per session, so the lock,
+// the work and the unlock must all share one connection. SQLite has no
+// advisory locks and a single writer anyway, so there fn just runs.
+func withLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	if !isPostgres(db) {
+		if err := ensureTable(db); err != nil {
+			return err
+		}
+		return fn(db)
+	}
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+			return fmt.Errorf("acquiring migration lock: %w", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", lockKey)
+
+		if err := ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func ensureTable(db *gorm.DB) error {
+	err := execAll(db, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name varchar(255) NOT NULL,
+		applied_at timestamptz NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func appliedVersions(db *gorm.DB) (map[int64]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("reading schema_migrations: %w", err)
+	}
+	done := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row
+	}
+	return done, nil
+}
+
+// isPostgres reports whether db talks to Postgres rather than SQLite.
+func isPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+// sqliteTypes maps the Postgres column types used by the migrations to
+// their SQLite equivalents.
+var sqliteTypes = strings.NewReplacer(
+	"bigserial PRIMARY KEY", "integer PRIMARY KEY AUTOINCREMENT",
+	"timestamptz", "datetime",
+)
+
+// execAll runs each statement in order, stopping at the first error. On
+// SQLite the Postgres column types are rewritten first, so plain DDL can be
+// shared between both databases.
+func execAll(tx *gorm.DB, statements ...string) error {
+	for _, stmt := range statements {
+		if !isPostgres(tx) {
+			stmt = sqliteTypes.Replace(stmt)
+		}
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// sqliteVariants stand in for the Up or Down of migrations whose Postgres
+// SQL SQLite cannot run. Applied migrations must never change, so SQLite
+// support for a migration that already shipped goes here, keyed by version,
+// rather than into its file. A nil Up or Down keeps the original.
+var sqliteVariants = map[int64]Migration{
+	// SQLite cannot change a column default, so the owner backfill is an
+	// UPDATE instead
+	4: {Up: func(tx *gorm.DB) error {
+		return execAll(tx,
+			`ALTER TABLE users ADD COLUMN role varchar(20) NOT NULL DEFAULT 'viewer'`,
+			`UPDATE users SET role = 'owner'`,
+			`ALTER TABLE api_keys ADD COLUMN role varchar(20) NOT NULL DEFAULT 'editor'`,
+			`UPDATE api_keys SET role = 'owner'`,
+		)
+	}},
+	// SQLite has no array type; StringArray stores the same array literal
+	// in a text column there
+	5: {Up: noop, Down: noop},
+	// SQLite has no tsvector; search matches in Go there instead
+	6: {Up: noop, Down: noop},
+}
+
+func noop(*gorm.DB) error { return nil }
+
+// forDialect returns m with its SQLite variant swapped in when db is
+// SQLite.
+func forDialect(db *gorm.DB, m Migration) Migration {
+	v, ok := sqliteVariants[m.Version]
+	if !ok || isPostgres(db) {
+		return m
+	}
+	if v.Up != nil {
+		m.Up = v.Up
+	}
+	if v.Down != nil {
+		m.Down = v.Down
+	}
+	return m
+}
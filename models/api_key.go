@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey is a long-lived credential for automation. Only the SHA-256 hash of
+// the key is stored; the plaintext is shown once when the key is minted.
+type APIKey struct {
+	gorm.Model
+	Name       string     `json:"name" gorm:"type:varchar(255);not null"`
+	Prefix     string     `json:"prefix" gorm:"type:varchar(16);not null"`
+	KeyHash    string     `json:"-" gorm:"type:char(64);not null;uniqueIndex"`
+	Role       string     `json:"role" gorm:"type:varchar(20);not null;default:editor"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
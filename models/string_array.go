@@ -0,0 +1,181 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// StringArray maps a Postgres text[] column. Elements are always written
+// quoted, so commas, quotes, backslashes and braces survive a round trip.
+type StringArray []string
+
+// Value implements the driver.Valuer interface, encoding the array as a
+// Postgres array literal. A nil array is stored as an empty one.
+func (a StringArray) Value() (driver.Value, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, s := range a {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('"')
+		for _, r := range s {
+			if r == '"' || r == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String(), nil
+}
+
+// Scan implements the sql.Scanner interface, decoding a one-dimensional
+// Postgres array literal. NULL elements decode as empty strings.
+func (a *StringArray) Scan(value interface{}) error {
+	var src string
+	switch v := value.(type) {
+	case nil:
+		*a = StringArray{}
+		return nil
+	case string:
+		src = v
+	case []byte:
+		src = string(v)
+	default:
+		return fmt.Errorf("failed to scan StringArray from %T", value)
+	}
+
+	parsed, err := parseArrayLiteral(src)
+	if err != nil {
+		return fmt.Errorf("failed to scan StringArray: %w", err)
+	}
+	*a = parsed
+	return nil
+}
+
+// MarshalJSON encodes a nil array as [] rather than null.
+func (a StringArray) MarshalJSON() ([]byte, error) {
+	if a == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(a))
+}
+
+// parseArrayLiteral decodes a one-dimensional array literal, rejecting
+// anything Postgres would reject as malformed, such as empty unquoted
+// elements ({a,}), unterminated quotes or text after a closing quote.
+func parseArrayLiteral(src string) (StringArray, error) {
+	// Skip an optional dimension decoration such as "[1:3]="
+	if strings.HasPrefix(src, "[") {
+		i := strings.IndexByte(src, '=')
+		if i < 0 {
+			return nil, errors.New("malformed dimension decoration")
+		}
+		src = src[i+1:]
+	}
+	if len(src) < 2 || src[0] != '{' || src[len(src)-1] != '}' {
+		return nil, fmt.Errorf("malformed array literal %q", src)
+	}
+	body := src[1 : len(src)-1]
+
+	result := StringArray{}
+	if strings.TrimSpace(body) == "" {
+		return result, nil
+	}
+
+	for i := 0; ; {
+		i = skipSpaces(body, i)
+		var elem string
+		var err error
+		if i < len(body) && body[i] == '"' {
+			elem, i, err = quotedElement(body, i+1)
+		} else {
+			elem, i, err = unquotedElement(body, i)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", len(result), err)
+		}
+		result = append(result, elem)
+
+		i = skipSpaces(body, i)
+		if i == len(body) {
+			return result, nil
+		}
+		if body[i] != ',' {
+			return nil, fmt.Errorf("unexpected %q after element %d", body[i], len(result)-1)
+		}
+		i++
+	}
+}
+
+// quotedElement reads a double-quoted element from just after its opening
+// quote and returns it with the index after the closing quote.
+func quotedElement(body string, i int) (string, int, error) {
+	var elem strings.Builder
+	for i < len(body) {
+		switch c := body[i]; c {
+		case '\\':
+			if i+1 == len(body) {
+				return "", 0, errors.New("unterminated quoted element")
+			}
+			elem.WriteByte(body[i+1])
+			i += 2
+		case '"':
+			return elem.String(), i + 1, nil
+		default:
+			elem.WriteByte(c)
+			i++
+		}
+	}
+	return "", 0, errors.New("unterminated quoted element")
+}
+
+// unquotedElement reads an unquoted element up to the next comma. Trailing
+// whitespace is dropped unless escaped, and an unescaped NULL decodes as an
+// empty string.
+func unquotedElement(body string, i int) (string, int, error) {
+	var elem strings.Builder
+	keep, escaped := 0, false
+	for i < len(body) && body[i] != ',' {
+		c := body[i]
+		switch {
+		case c == '{' || c == '}' || c == '"':
+			return "", 0, fmt.Errorf("unexpected %q in unquoted element", c)
+		case c == '\\':
+			if i+1 == len(body) {
+				return "", 0, errors.New("trailing backslash")
+			}
+			elem.WriteByte(body[i+1])
+			i += 2
+			keep, escaped = elem.Len(), true
+			continue
+		}
+		elem.WriteByte(c)
+		if c != ' ' {
+			keep = elem.Len()
+		}
+		i++
+	}
+
+	s := elem.String()[:keep]
+	if s == "" {
+		return "", 0, errors.New("empty unquoted element")
+	}
+	if !escaped && strings.EqualFold(s, "NULL") {
+		s = ""
+	}
+	return s, i, nil
+}
+
+// skipSpaces returns the index of the first non-space byte from i.
+func skipSpaces(s string, i int) int {
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	return i
+}
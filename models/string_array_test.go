@@ -0,0 +1,135 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStringArrayScan(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want StringArray
+	}{
+		{"empty", `{}`, StringArray{}},
+		{"blank", `{ }`, StringArray{}},
+		{"unquoted", `{Go,TypeScript}`, StringArray{"Go", "TypeScript"}},
+		{"spaces around elements", `{ Go , Type Script }`, StringArray{"Go", "Type Script"}},
+		{"quoted", `{"Go","C, C++"}`, StringArray{"Go", "C, C++"}},
+		{"quoted keeps spaces", `{" padded "}`, StringArray{" padded "}},
+		{"quoted empty element", `{"",a}`, StringArray{"", "a"}},
+		{"escaped quote and backslash", `{"say \"hi\"","C:\\path"}`, StringArray{`say "hi"`, `C:\path`}},
+		{"quoted braces", `{"{braces}"}`, StringArray{"{braces}"}},
+		{"unquoted escapes", `{a\,b,c\"d,e\ }`, StringArray{"a,b", `c"d`, "e "}},
+		{"NULL", `{NULL,null,a}`, StringArray{"", "", "a"}},
+		{"quoted NULL is text", `{"NULL"}`, StringArray{"NULL"}},
+		{"escaped NULL is text", `{\NULL}`, StringArray{"NULL"}},
+		{"unicode", `{"Ünïcode ✓",日本}`, StringArray{"Ünïcode ✓", "日本"}},
+		{"dimension decoration", `[1:2]={a,b}`, StringArray{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, src := range []interface{}{tt.src, []byte(tt.src)} {
+				var got StringArray
+				if err := got.Scan(src); err != nil {
+					t.Fatalf("Scan(%T): %v", src, err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Scan(%T) = %q, want %q", src, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestStringArrayScanMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		src  interface{}
+	}{
+		{"no braces", `a,b`},
+		{"unclosed", `{a,b`},
+		{"empty string", ``},
+		{"trailing comma", `{a,}`},
+		{"leading comma", `{,a}`},
+		{"double comma", `{a,,b}`},
+		{"blank element", `{a, ,b}`},
+		{"unterminated quote", `{"a,b}`},
+		{"escaped closing quote", `{"a\"}`},
+		{"text after quote", `{"a"b}`},
+		{"quote inside unquoted", `{a"b"}`},
+		{"trailing backslash", `{a\}`},
+		{"nested", `{{a,b},{c,d}}`},
+		{"bad decoration", `[1:2{a}`},
+		{"unsupported type", 42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got StringArray
+			if err := got.Scan(tt.src); err == nil {
+				t.Errorf("Scan(%q) = %q, want an error", tt.src, got)
+			}
+		})
+	}
+}
+
+func TestStringArrayScanNil(t *testing.T) {
+	got := StringArray{"stale"}
+	if err := got.Scan(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || len(got) != 0 {
+		t.Errorf("Scan(nil) = %#v, want an empty array", got)
+	}
+}
+
+func TestStringArrayValueRoundTrip(t *testing.T) {
+	tests := []StringArray{
+		nil,
+		{},
+		{"Go"},
+		{"", "NULL", "null"},
+		{"C, C++", `say "hi"`, `C:\path\`, "{braces}", " padded ", "Ünïcode ✓"},
+	}
+	for _, in := range tests {
+		v, err := in.Value()
+		if err != nil {
+			t.Fatalf("Value(%q): %v", in, err)
+		}
+		var out StringArray
+		if err := out.Scan(v); err != nil {
+			t.Fatalf("Scan(%q): %v", v, err)
+		}
+		want := in
+		if want == nil {
+			want = StringArray{}
+		}
+		if !reflect.DeepEqual(out, want) {
+			t.Errorf("round trip of %q through %q = %q", in, v, out)
+		}
+	}
+
+	if v, _ := (StringArray{"a", `b"c`}).Value(); v != `{"a","b\"c"}` {
+		t.Errorf("Value() = %q, want every element quoted", v)
+	}
+}
+
+func TestStringArrayMarshalJSON(t *testing.T) {
+	for _, tt := range []struct {
+		in   StringArray
+		want string
+	}{
+		{nil, `[]`},
+		{StringArray{}, `[]`},
+		{StringArray{"a", "b"}, `["a","b"]`},
+	} {
+		got, err := json.Marshal(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Marshal(%#v) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// User is an account that can sign in to manage the portfolio content.
+type User struct {
+	gorm.Model
+	Email        string `json:"email" gorm:"type:varchar(255);not null;uniqueIndex:idx_users_email,where:deleted_at IS NULL"`
+	PasswordHash string `json:"-" gorm:"type:varchar(255);not null"`
+	Role         string `json:"role" gorm:"type:varchar(20);not null;default:viewer"`
+}
+
+func (User) TableName() string {
+	return "users"
+}
+
+// RefreshToken is one link in a chain of rotating refresh tokens. Every
+// token issued from the same login shares a FamilyID, so presenting an
+// already used token revokes the whole chain.
+type RefreshToken struct {
+	gorm.Model
+	UserID    uint      `gorm:"not null;index"`
+	FamilyID  string    `gorm:"type:varchar(36);not null;index"`
+	TokenHash string    `gorm:"type:char(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
@@ -0,0 +1,341 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// operation is one entry of an RFC 6902 patch. Value stays raw so a
+// missing value can be told apart from null.
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// JSONPatch applies an RFC 6902 patch: a list of add, remove, replace,
+// move, copy and test operations run in order. The patch is atomic; if any
+// operation fails, the error names it and the document is not returned.
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	var ops []operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, invalidf("JSON patch must be an array of operations: %v", err)
+	}
+
+	for i, op := range ops {
+		target, err = apply(target, op)
+		if err != nil {
+			e := err.(*Error)
+			return nil, &Error{Conflict: e.Conflict, Msg: fmt.Sprintf("operation %d (%s): %s", i, op.Op, e.Msg)}
+		}
+	}
+	return json.Marshal(target)
+}
+
+func apply(doc interface{}, op operation) (interface{}, error) {
+	if op.Path == nil {
+		return nil, invalidf("path is required")
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, invalidf("value is required")
+		}
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, invalidf("value is not valid JSON: %v", err)
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			return replace(doc, path, value)
+		}
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, conflictf("value at %q does not match", *op.Path)
+		}
+		return doc, nil
+
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+
+	case "move", "copy":
+		if op.From == nil {
+			return nil, invalidf("from is required")
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			value, err := get(doc, from)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, path, deepCopy(value))
+		}
+		if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+			return nil, invalidf("cannot move %q into one of its own children", *op.From)
+		}
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+
+	default:
+		return nil, invalidf("unknown op %q", op.Op)
+	}
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into unescaped reference
+// tokens. The empty pointer refers to the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, invalidf("path %q must be empty or start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// get returns the value path refers to.
+func get(doc interface{}, path []string) (interface{}, error) {
+	for i, token := range path {
+		var err error
+		if doc, err = child(doc, token, pointerString(path[:i+1])); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// child returns the member or element token names in node.
+func child(node interface{}, token, at string) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		value, ok := n[token]
+		if !ok {
+			return nil, conflictf("%q does not exist", at)
+		}
+		return value, nil
+	case []interface{}:
+		i, err := index(token, len(n)-1, at)
+		if err != nil {
+			return nil, err
+		}
+		return n[i], nil
+	default:
+		return nil, conflictf("%q does not exist, its parent is not an object or array", at)
+	}
+}
+
+// update calls fn on the container holding the last token of path and
+// stores the container fn returns back into doc. Arrays change length, so
+// every container on the way is written back.
+func update(doc interface{}, path []string, depth int, fn func(parent interface{}, token, at string) (interface{}, error)) (interface{}, error) {
+	at := pointerString(path[:depth+1])
+	if depth == len(path)-1 {
+		return fn(doc, path[depth], at)
+	}
+	next, err := child(doc, path[depth], at)
+	if err != nil {
+		return nil, err
+	}
+	next, err = update(next, path, depth+1, fn)
+	if err != nil {
+		return nil, err
+	}
+	switch n := doc.(type) {
+	case map[string]interface{}:
+		n[path[depth]] = next
+	case []interface{}:
+		i, _ := strconv.Atoi(path[depth])
+		n[i] = next
+	}
+	return doc, nil
+}
+
+// add inserts value at path. In arrays it shifts later elements up, and the
+// token - appends.
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(doc, path, 0, func(parent interface{}, token, at string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[token] = value
+			return p, nil
+		case []interface{}:
+			if token == "-" {
+				return append(p, value), nil
+			}
+			i, err := index(token, len(p), at)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, nil)
+			copy(p[i+1:], p[i:])
+			p[i] = value
+			return p, nil
+		default:
+			return nil, conflictf("cannot add %q, its parent is not an object or array", at)
+		}
+	})
+}
+
+// remove deletes the value at path and returns it. In arrays it shifts
+// later elements down.
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, invalidf("cannot remove the whole document")
+	}
+	var removed interface{}
+	doc, err := update(doc, path, 0, func(parent interface{}, token, at string) (interface{}, error) {
+		value, err := child(parent, token, at)
+		if err != nil {
+			return nil, err
+		}
+		removed = value
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			delete(p, token)
+			return p, nil
+		default:
+			elems := parent.([]interface{})
+			i, _ := strconv.Atoi(token)
+			return append(elems[:i], elems[i+1:]...), nil
+		}
+	})
+	return doc, removed, err
+}
+
+// replace sets an existing value at path.
+func replace(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(doc, path, 0, func(parent interface{}, token, at string) (interface{}, error) {
+		if _, err := child(parent, token, at); err != nil {
+			return nil, err
+		}
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[token] = value
+			return p, nil
+		default:
+			elems := parent.([]interface{})
+			i, _ := strconv.Atoi(token)
+			elems[i] = value
+			return elems, nil
+		}
+	})
+}
+
+// index parses an array index token, which must be a plain decimal number
+// no greater than max.
+func index(token string, max int, at string) (int, error) {
+	if token == "" || strings.Trim(token, "0123456789") != "" || (len(token) > 1 && token[0] == '0') {
+		return 0, invalidf("%q is not a valid array index", at)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, conflictf("%q is out of range", at)
+	}
+	if i > max {
+		return 0, conflictf("%q is out of range", at)
+	}
+	return i, nil
+}
+
+func pointerString(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
+
+// equal compares decoded JSON values, treating numbers as equal when they
+// have the same value regardless of spelling (1 and 1.0).
+func equal(a, b interface{}) bool {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aErr := av.Float64()
+		bf, bErr := bv.Float64()
+		return aErr == nil && bErr == nil && af == bf
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, value := range av {
+			other, ok := bv[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(vv))
+		for key, value := range vv {
+			out[key] = deepCopy(value)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(vv))
+		for i, value := range vv {
+			out[i] = deepCopy(value)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,102 @@
+// Package patch applies JSON Merge Patch (RFC 7396) and JSON Patch
+// (RFC 6902) documents to JSON values.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Media types of the supported patch formats.
+const (
+	MIMEMergePatch = "application/merge-patch+json"
+	MIMEJSONPatch  = "application/json-patch+json"
+)
+
+// ErrUnsupportedMediaType is returned by Apply for any other media type.
+var ErrUnsupportedMediaType = errors.New("patch: unsupported media type")
+
+// Error explains why a patch was rejected. The message is safe to show to
+// clients. Conflict is set when the patch is well formed but does not fit
+// the document, e.g. a path that does not exist or a failed test.
+type Error struct {
+	Conflict bool
+	Msg      string
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+func invalidf(format string, args ...interface{}) *Error {
+	return &Error{Msg: fmt.Sprintf(format, args...)}
+}
+
+func conflictf(format string, args ...interface{}) *Error {
+	return &Error{Conflict: true, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Apply applies patch, in the format named by mediaType, to doc and
+// returns the patched document.
+func Apply(mediaType string, doc, patch []byte) ([]byte, error) {
+	switch mediaType {
+	case MIMEMergePatch:
+		return Merge(doc, patch)
+	case MIMEJSONPatch:
+		return JSONPatch(doc, patch)
+	default:
+		return nil, ErrUnsupportedMediaType
+	}
+}
+
+// Merge applies an RFC 7396 merge patch: objects are merged recursively,
+// null removes a member and anything else, arrays included, replaces the
+// target value.
+func Merge(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("patch: decoding document: %w", err)
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, invalidf("merge patch is not valid JSON: %v", err)
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = merge(t[key], value)
+	}
+	return t
+}
+
+// decode parses exactly one JSON value, keeping numbers as json.Number so
+// they are written back unchanged.
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return v, nil
+}
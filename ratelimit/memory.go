@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many hits pass between sweeps of expired windows.
+const sweepEvery = 1024
+
+// MemoryStore keeps counters in process memory. Each instance counts on
+// its own, so behind a load balancer every instance grants the full budget.
+type MemoryStore struct {
+	mu      sync.Mutex
+	windows map[string]*memoryWindow
+	hits    int
+}
+
+type memoryWindow struct {
+	count int64
+	reset time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{windows: map[string]*memoryWindow{}}
+}
+
+func (s *MemoryStore) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.hits++
+	if s.hits%sweepEvery == 0 {
+		for k, w := range s.windows {
+			if !now.Before(w.reset) {
+				delete(s.windows, k)
+			}
+		}
+	}
+
+	w := s.windows[key]
+	if w == nil || !now.Before(w.reset) {
+		w = &memoryWindow{reset: now.Add(window)}
+		s.windows[key] = w
+	}
+	w.count++
+	return w.count, w.reset, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
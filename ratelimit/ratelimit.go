@@ -0,0 +1,94 @@
+// Package ratelimit counts requests per client in fixed windows, with the
+// counters kept in process memory or in a Redis-compatible server shared
+// by every instance.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Stores accepted by Config.Store.
+const (
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+)
+
+// Config holds the budgets and where their counters are kept.
+type Config struct {
+	Store    string // memory or redis
+	RedisURL string // redis://[user:password@]host:port/db, for the redis store
+	Window   time.Duration
+	// Reads and Writes are the requests a client may make per window. Zero
+	// turns that budget off.
+	Reads  int
+	Writes int
+}
+
+// Store counts hits per key in fixed windows.
+type Store interface {
+	// Hit records a hit on key, opening a window of the given length if none
+	// is open, and returns the hits so far in the window and when it ends.
+	Hit(ctx context.Context, key string, window time.Duration) (count int64, reset time.Time, err error)
+	Close() error
+}
+
+// NewStore opens the store named by cfg.Store.
+func NewStore(cfg Config) (Store, error) {
+	switch cfg.Store {
+	case StoreMemory:
+		return NewMemoryStore(), nil
+	case StoreRedis:
+		return NewRedisStore(cfg.RedisURL)
+	}
+	return nil, fmt.Errorf("unknown rate limit store %q (expected memory or redis)", cfg.Store)
+}
+
+// Decision is the outcome of one request against its budget.
+type Decision struct {
+	Allowed   bool
+	Limit     int64
+	Remaining int64
+	// Reset is how long until the window ends and the budget refills.
+	Reset  time.Duration
+	Window time.Duration
+}
+
+// Limiter applies the read and write budgets of a Config.
+type Limiter struct {
+	store  Store
+	window time.Duration
+	reads  int64
+	writes int64
+}
+
+func New(store Store, cfg Config) *Limiter {
+	return &Limiter{store: store, window: cfg.Window, reads: int64(cfg.Reads), writes: int64(cfg.Writes)}
+}
+
+// Allow counts a request by client against its read or write budget. The
+// returned bool is false when that budget is turned off, in which case the
+// Decision is meaningless.
+func (l *Limiter) Allow(ctx context.Context, client string, write bool) (Decision, bool, error) {
+	budget, limit := "read", l.reads
+	if write {
+		budget, limit = "write", l.writes
+	}
+	if limit <= 0 {
+		return Decision{}, false, nil
+	}
+
+	count, reset, err := l.store.Hit(ctx, budget+":"+client, l.window)
+	if err != nil {
+		return Decision{}, true, err
+	}
+	d := Decision{
+		Allowed:   count <= limit,
+		Limit:     limit,
+		Remaining: max(limit-count, 0),
+		Reset:     max(time.Until(reset), 0),
+		Window:    l.window,
+	}
+	return d, true, nil
+}
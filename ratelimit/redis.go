@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces the counters in a Redis server shared with others.
+const keyPrefix = "wandhx:ratelimit:"
+
+// hitScript increments the counter and starts its window on the first hit,
+// atomically, and returns the count and the milliseconds left. A counter
+// that somehow lost its expiry gets a fresh one rather than living forever.
+var hitScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// Client defaults for the store. The limiter runs in front of every request
+// and lets requests through when the server fails, so a slow or unreachable
+// server must cost milliseconds rather than go-redis's default seconds of
+// timeouts and retries.
+const (
+	redisDialTimeout = 100 * time.Millisecond
+	redisIOTimeout   = 50 * time.Millisecond
+)
+
+// RedisStore keeps counters in a Redis-compatible server (Redis, Valkey,
+// KeyDB, ...), so every instance behind a load balancer shares one budget.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore connects to the server at url, such as
+// redis://:password@localhost:6379/0. Timeouts and retries set in the URL
+// (dial_timeout, read_timeout, write_timeout, pool_timeout, max_retries)
+// override the short defaults.
+func NewRedisStore(url string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("parsing rate limit redis URL: %w", err)
+	}
+	if opts.DialTimeout == 0 {
+		opts.DialTimeout = redisDialTimeout
+	}
+	if opts.ReadTimeout == 0 {
+		opts.ReadTimeout = redisIOTimeout
+	}
+	if opts.WriteTimeout == 0 {
+		opts.WriteTimeout = redisIOTimeout
+	}
+	if opts.PoolTimeout == 0 {
+		opts.PoolTimeout = redisDialTimeout
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = -1 // go-redis reads 0 as "the default of 3"
+	}
+	return &RedisStore{client: redis.NewClient(opts)}, nil
+}
+
+// Ping checks that the server is reachable.
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *RedisStore) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	res, err := hitScript.Run(ctx, s.client, []string{keyPrefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if len(res) != 2 {
+		return 0, time.Time{}, fmt.Errorf("rate limit script returned %d values, want 2", len(res))
+	}
+	return res[0], time.Now().Add(time.Duration(res[1]) * time.Millisecond), nil
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
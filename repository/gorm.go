@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"wannn-site-rebuild-api/listing"
+	"wannn-site-rebuild-api/models"
+)
+
+// NewGORM returns repositories backed by db.
+func NewGORM(db *gorm.DB) Repositories {
+	return Repositories{
+		Projects:        &gormProjects{gormCRUD[models.Project]{db: db, spec: ProjectListing}},
+		Experiences:     &gormExperiences{gormCRUD[models.Experience]{db: db, spec: ExperienceListing}},
+		SkillCategories: &gormSkillCategories{gormCRUD[models.SkillCategory]{db: db, spec: SkillCategoryListing}},
+		Users:           &gormUsers{db: db},
+		APIKeys:         &gormAPIKeys{db: db},
+		RefreshTokens:   &gormRefreshTokens{db: db},
+		Search:          &gormSearch{db: db},
+	}
+}
+
+// gormCRUD implements CRUD for a GORM model with soft deletes.
+type gormCRUD[T any] struct {
+	db   *gorm.DB
+	spec listing.Spec
+}
+
+func (r *gormCRUD[T]) List(ctx context.Context, q listing.Query) ([]T, int64, error) {
+	db := r.db.WithContext(ctx)
+
+	var total int64
+	if err := listing.Where(db.Model(new(T)), r.spec, q).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []T
+	if err := listing.Paginate(listing.Where(db, r.spec, q), r.spec, q).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+func (r *gormCRUD[T]) Get(ctx context.Context, id uint) (*T, error) {
+	item := new(T)
+	if err := r.db.WithContext(ctx).First(item, id).Error; err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (r *gormCRUD[T]) Create(ctx context.Context, item *T) error {
+	return r.db.WithContext(ctx).Create(item).Error
+}
+
+func (r *gormCRUD[T]) Update(ctx context.Context, item *T) error {
+	version := versionOf(item)
+	read := *version
+	*version = read + 1
+
+	// Unlike Save, an explicit Select never falls back to inserting the
+	// row when no row matched
+	result := r.db.WithContext(ctx).Model(item).Select("*").Where("version = ?", read).Updates(item)
+	err := result.Error
+	if err == nil && result.RowsAffected == 0 {
+		err = r.missingOrChanged(ctx, modelOf(item).ID, false)
+	}
+	if err != nil {
+		*version = read
+	}
+	return err
+}
+
+func (r *gormCRUD[T]) Delete(ctx context.Context, id, version uint) error {
+	return r.deleteVersion(ctx, id, version, false)
+}
+
+// deleteVersion deletes the row if it still has the given version: softly,
+// or permanently when unscoped, which also matches deleted rows.
+func (r *gormCRUD[T]) deleteVersion(ctx context.Context, id, version uint, unscoped bool) error {
+	result := r.scope(ctx, unscoped).Where("version = ?", version).Delete(new(T), id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return r.missingOrChanged(ctx, id, unscoped)
+	}
+	return nil
+}
+
+// missingOrChanged explains why a versioned write matched no row.
+func (r *gormCRUD[T]) missingOrChanged(ctx context.Context, id uint, unscoped bool) error {
+	var n int64
+	if err := r.scope(ctx, unscoped).Model(new(T)).Where("id = ?", id).Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return ErrVersionMismatch
+}
+
+func (r *gormCRUD[T]) Count(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(new(T)).Count(&n).Error
+	return n, err
+}
+
+func (r *gormCRUD[T]) NextPosition(ctx context.Context) (int, error) {
+	return nextPosition[T](r.db.WithContext(ctx))
+}
+
+// nextPosition is the position after the last live row of T.
+func nextPosition[T any](db *gorm.DB) (int, error) {
+	var last *int
+	err := db.Model(new(T)).Select("MAX(position)").Scan(&last).Error
+	if err != nil || last == nil {
+		return 0, err
+	}
+	return *last + 1, nil
+}
+
+func (r *gormCRUD[T]) Reorder(ctx context.Context, order []OrderItem) ([]T, error) {
+	var items []T
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&items).Error; err != nil {
+			return err
+		}
+		positions, err := newPositions(items, order)
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			id := modelOf(&items[i]).ID
+			if *positionOf(&items[i]) == positions[id] {
+				continue
+			}
+			err := tx.Model(new(T)).Where("id = ?", id).
+				Updates(map[string]interface{}{"position": positions[id], "version": gorm.Expr("version + 1")}).Error
+			if err != nil {
+				return err
+			}
+		}
+		items = nil
+		return tx.Order("position, id").Find(&items).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *gormCRUD[T]) Trash(ctx context.Context, q listing.Query) ([]T, int64, error) {
+	trashed := func() *gorm.DB {
+		return r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL")
+	}
+
+	var total int64
+	if err := listing.Where(trashed().Model(new(T)), r.spec, q).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []T
+	if err := listing.Paginate(listing.Where(trashed(), r.spec, q), r.spec, q).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+func (r *gormCRUD[T]) GetIncludingTrash(ctx context.Context, id uint) (*T, error) {
+	item := new(T)
+	if err := r.db.WithContext(ctx).Unscoped().First(item, id).Error; err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (r *gormCRUD[T]) Restore(ctx context.Context, id uint) (*T, error) {
+	var item T
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The old position may belong to another row by now, so a
+		// restored row goes last
+		position, err := nextPosition[T](tx)
+		if err != nil {
+			return err
+		}
+		result := tx.Unscoped().Model(new(T)).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "position": position, "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.First(&item, id).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *gormCRUD[T]) Purge(ctx context.Context, id, version uint) error {
+	return r.deleteVersion(ctx, id, version, true)
+}
+
+func (r *gormCRUD[T]) PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Unscoped().Where("deleted_at < ?", cutoff).Delete(new(T))
+	return result.RowsAffected, result.Error
+}
+
+// scope returns a session seeing live rows, or every row when unscoped.
+func (r *gormCRUD[T]) scope(ctx context.Context, unscoped bool) *gorm.DB {
+	db := r.db.WithContext(ctx)
+	if unscoped {
+		db = db.Unscoped()
+	}
+	return db
+}
+
+// first returns the first live row matching the conditions.
+func (r *gormCRUD[T]) first(ctx context.Context, query string, args ...interface{}) (*T, error) {
+	item := new(T)
+	if err := r.db.WithContext(ctx).Where(query, args...).Order("id").First(item).Error; err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+type gormProjects struct {
+	gormCRUD[models.Project]
+}
+
+func (r *gormProjects) FindByTitle(ctx context.Context, title string) (*models.Project, error) {
+	return r.first(ctx, "title = ?", title)
+}
+
+type gormExperiences struct {
+	gormCRUD[models.Experience]
+}
+
+func (r *gormExperiences) FindByTitleAndCompany(ctx context.Context, title, company string) (*models.Experience, error) {
+	return r.first(ctx, "title = ? AND company = ?", title, company)
+}
+
+type gormSkillCategories struct {
+	gormCRUD[models.SkillCategory]
+}
+
+func (r *gormSkillCategories) FindByTitle(ctx context.Context, title string) (*models.SkillCategory, error) {
+	return r.first(ctx, "title = ?", title)
+}
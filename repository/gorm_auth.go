@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"wannn-site-rebuild-api/auth"
+	"wannn-site-rebuild-api/models"
+)
+
+type gormUsers struct {
+	db *gorm.DB
+}
+
+func (r *gormUsers) List(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Order("id").Find(&users).Error
+	return users, err
+}
+
+func (r *gormUsers) Get(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *gormUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *gormUsers) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *gormUsers) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		owners, err := lockOwners(tx)
+		if err != nil {
+			return err
+		}
+		var current models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, user.ID).Error; err != nil {
+			return err
+		}
+		if current.Role == string(auth.RoleOwner) && user.Role != string(auth.RoleOwner) {
+			if err := ensureAnotherOwner(owners, user.ID); err != nil {
+				return err
+			}
+		}
+		return tx.Save(user).Error
+	})
+}
+
+func (r *gormUsers) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		owners, err := lockOwners(tx)
+		if err != nil {
+			return err
+		}
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+			return err
+		}
+		if user.Role == string(auth.RoleOwner) {
+			if err := ensureAnotherOwner(owners, user.ID); err != nil {
+				return err
+			}
+		}
+		// End every session of the account along with it
+		err = tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&user).Error
+	})
+}
+
+// lockOwners locks the rows of every live owner, in id order, and returns
+// their IDs. Transactions that may remove an owner call it before locking
+// anything else, so two of them cannot each count the other's owner and
+// remove both, nor deadlock on each other's rows.
+func lockOwners(tx *gorm.DB) ([]uint, error) {
+	var ids []uint
+	err := tx.Model(&models.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ?", auth.RoleOwner).
+		Order("id").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ensureAnotherOwner fails with ErrLastOwner unless owners holds an owner
+// other than userID.
+func ensureAnotherOwner(owners []uint, userID uint) error {
+	for _, id := range owners {
+		if id != userID {
+			return nil
+		}
+	}
+	return ErrLastOwner
+}
+
+type gormAPIKeys struct {
+	db *gorm.DB
+}
+
+func (r *gormAPIKeys) Create(ctx context.Context, key *models.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *gormAPIKeys) List(ctx context.Context) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.WithContext(ctx).Order("id").Find(&keys).Error
+	return keys, err
+}
+
+func (r *gormAPIKeys) FindActive(ctx context.Context, hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.WithContext(ctx).Where("key_hash = ? AND revoked_at IS NULL", hash).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *gormAPIKeys) MarkUsed(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error
+}
+
+func (r *gormAPIKeys) Revoke(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type gormRefreshTokens struct {
+	db *gorm.DB
+}
+
+func (r *gormRefreshTokens) Create(ctx context.Context, token *models.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *gormRefreshTokens) Consume(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	reused := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the row so two concurrent refreshes cannot both succeed
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hash).
+			First(&token).Error
+		if err != nil {
+			return err
+		}
+		if token.UsedAt != nil || token.RevokedAt != nil {
+			reused = true
+			return nil
+		}
+		now := time.Now()
+		token.UsedAt = &now
+		return tx.Model(&token).Update("used_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		// Revoke outside the transaction above so the revocation sticks
+		if err := r.RevokeFamily(ctx, hash); err != nil {
+			return nil, err
+		}
+		return nil, ErrTokenReused
+	}
+	return &token, nil
+}
+
+func (r *gormRefreshTokens) RevokeFamily(ctx context.Context, hash string) error {
+	db := r.db.WithContext(ctx)
+	var token models.RefreshToken
+	err := db.Where("token_hash = ?", hash).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", token.FamilyID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+	"wannn-site-rebuild-api/models"
+)
+
+// searchSQL ranks projects, experiences and skill categories against a
+// websearch_to_tsquery using the search_vector columns added by migration
+// 6. Parameters: query text, allowed types, limit.
+var searchSQL = `
+WITH query AS (SELECT websearch_to_tsquery('english', ?) AS q)
+SELECT type, id, title, snippet, rank FROM (
+	SELECT 'project' AS type, p.id, p.title,
+		ts_headline('english', ` + escapeHTML(`p.description || ' ' || search_array_text(p.technologies)`) + `, query.q, ` + headlineOptions + `) AS snippet,
+		ts_rank(p.search_vector, query.q) AS rank
+	FROM projects p, query
+	WHERE p.deleted_at IS NULL AND p.search_vector @@ query.q
+	UNION ALL
+	SELECT 'experience', e.id, e.title,
+		ts_headline('english', ` + escapeHTML(`e.company || ': ' || array_to_string(e.description, ' · ')`) + `, query.q, ` + headlineOptions + `),
+		ts_rank(e.search_vector, query.q)
+	FROM experiences e, query
+	WHERE e.deleted_at IS NULL AND e.search_vector @@ query.q
+	UNION ALL
+	SELECT 'skill', s.id, s.title,
+		ts_headline('english', ` + escapeHTML(`array_to_string(s.skills, ', ')`) + `, query.q, ` + headlineOptions + `),
+		ts_rank(s.search_vector, query.q)
+	FROM skill_categories s, query
+	WHERE s.deleted_at IS NULL AND s.search_vector @@ query.q
+) results
+WHERE type IN ?
+ORDER BY rank DESC, type, id
+LIMIT ?`
+
+const headlineOptions = `'StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=" … "'`
+
+// escapeHTML wraps a SQL text expression so it evaluates to the text with
+// HTML special characters escaped, like html.EscapeString. Snippets are
+// built from escaped text so the <mark> tags ts_headline adds are the only
+// markup in them.
+func escapeHTML(expr string) string {
+	for _, r := range [][2]string{{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {`"`, "&#34;"}, {"'", "&#39;"}} {
+		expr = "replace(" + expr + ", '" + strings.ReplaceAll(r[0], "'", "''") + "', '" + r[1] + "')"
+	}
+	return expr
+}
+
+type gormSearch struct {
+	db *gorm.DB
+}
+
+func (r *gormSearch) Search(ctx context.Context, query string, types []string, limit int) ([]SearchResult, error) {
+	db := r.db.WithContext(ctx)
+	if db.Dialector.Name() != "postgres" {
+		return r.searchText(db, query, types, limit)
+	}
+
+	results := []SearchResult{}
+	err := db.Raw(searchSQL, query, types, limit).Scan(&results).Error
+	return results, err
+}
+
+// searchText loads every row and matches in Go. It serves SQLite, which has
+// no tsvector; the content tables are small enough for that.
+func (r *gormSearch) searchText(db *gorm.DB, query string, types []string, limit int) ([]SearchResult, error) {
+	var projects []models.Project
+	var experiences []models.Experience
+	var skills []models.SkillCategory
+	for _, dest := range []interface{}{&projects, &experiences, &skills} {
+		if err := db.Order("id").Find(dest).Error; err != nil {
+			return nil, err
+		}
+	}
+	return searchText(query, types, limit, projects, experiences, skills), nil
+}
@@ -0,0 +1,376 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"wannn-site-rebuild-api/listing"
+	"wannn-site-rebuild-api/models"
+)
+
+// NewMemory returns repositories that keep everything in process memory.
+// Nothing is persisted; it is meant for tests and local experiments.
+func NewMemory() Repositories {
+	s := &memoryStore{}
+	return Repositories{
+		Projects:        &memoryProjects{memoryCRUD[models.Project]{s: s, table: &s.projects, spec: ProjectListing}},
+		Experiences:     &memoryExperiences{memoryCRUD[models.Experience]{s: s, table: &s.experiences, spec: ExperienceListing}},
+		SkillCategories: &memorySkillCategories{memoryCRUD[models.SkillCategory]{s: s, table: &s.skillCategories, spec: SkillCategoryListing}},
+		Users:           &memoryUsers{s: s},
+		APIKeys:         &memoryAPIKeys{s: s},
+		RefreshTokens:   &memoryRefreshTokens{s: s},
+		Search:          &memorySearch{s: s},
+	}
+}
+
+// memoryStore holds every table behind one lock, so operations spanning
+// tables are as atomic as their SQL transactions.
+type memoryStore struct {
+	mu              sync.RWMutex
+	projects        memoryTable[models.Project]
+	experiences     memoryTable[models.Experience]
+	skillCategories memoryTable[models.SkillCategory]
+	users           memoryTable[models.User]
+	apiKeys         memoryTable[models.APIKey]
+	refreshTokens   memoryTable[models.RefreshToken]
+}
+
+// memoryTable stores rows of a model embedding gorm.Model in insertion
+// order. Callers hold the store lock. Rows handed out are copies, so
+// callers cannot modify stored rows behind the table's back.
+type memoryTable[T any] struct {
+	rows   []*T
+	nextID uint
+}
+
+func (t *memoryTable[T]) insert(item *T) {
+	t.nextID++
+	now := time.Now()
+	m := modelOf(item)
+	m.ID, m.CreatedAt, m.UpdatedAt, m.DeletedAt = t.nextID, now, now, gorm.DeletedAt{}
+	t.rows = append(t.rows, clone(item))
+}
+
+// find returns the stored row with the given id, or nil if there is none
+// or it was deleted.
+func (t *memoryTable[T]) find(id uint) *T {
+	for _, row := range t.rows {
+		if m := modelOf(row); m.ID == id && !m.DeletedAt.Valid {
+			return row
+		}
+	}
+	return nil
+}
+
+// findAny returns the stored row with the given id, live or deleted, or
+// nil if there is none.
+func (t *memoryTable[T]) findAny(id uint) *T {
+	for _, row := range t.rows {
+		if modelOf(row).ID == id {
+			return row
+		}
+	}
+	return nil
+}
+
+// where returns the stored live rows for which match returns true.
+func (t *memoryTable[T]) where(match func(*T) bool) []*T {
+	var rows []*T
+	for _, row := range t.rows {
+		if !modelOf(row).DeletedAt.Valid && (match == nil || match(row)) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// live returns copies of every live row.
+func (t *memoryTable[T]) live() []T {
+	items := []T{}
+	for _, row := range t.where(nil) {
+		items = append(items, *clone(row))
+	}
+	return items
+}
+
+func (t *memoryTable[T]) update(item *T) error {
+	row := t.find(modelOf(item).ID)
+	if row == nil {
+		return ErrNotFound
+	}
+	modelOf(item).CreatedAt = modelOf(row).CreatedAt
+	modelOf(item).UpdatedAt = time.Now()
+	*row = *clone(item)
+	return nil
+}
+
+// trashed returns copies of every deleted row.
+func (t *memoryTable[T]) trashed() []T {
+	items := []T{}
+	for _, row := range t.rows {
+		if modelOf(row).DeletedAt.Valid {
+			items = append(items, *clone(row))
+		}
+	}
+	return items
+}
+
+// purge removes the rows for which match returns true and returns how
+// many there were.
+func (t *memoryTable[T]) purge(match func(*T) bool) int64 {
+	kept := t.rows[:0]
+	for _, row := range t.rows {
+		if !match(row) {
+			kept = append(kept, row)
+		}
+	}
+	n := int64(len(t.rows) - len(kept))
+	for i := len(kept); i < len(t.rows); i++ {
+		t.rows[i] = nil
+	}
+	t.rows = kept
+	return n
+}
+
+func (t *memoryTable[T]) delete(id uint) error {
+	row := t.find(id)
+	if row == nil {
+		return ErrNotFound
+	}
+	modelOf(row).DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return nil
+}
+
+// modelOf returns the gorm.Model embedded in item.
+func modelOf[T any](item *T) *gorm.Model {
+	return reflect.ValueOf(item).Elem().FieldByName("Model").Addr().Interface().(*gorm.Model)
+}
+
+// clone copies item along with its slice fields.
+func clone[T any](item *T) *T {
+	c := *item
+	v := reflect.ValueOf(&c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() == reflect.Slice && !f.IsNil() {
+			s := reflect.MakeSlice(f.Type(), f.Len(), f.Len())
+			reflect.Copy(s, f)
+			f.Set(s)
+		}
+	}
+	return &c
+}
+
+// memoryCRUD implements CRUD on a memoryTable.
+type memoryCRUD[T any] struct {
+	s     *memoryStore
+	table *memoryTable[T]
+	spec  listing.Spec
+}
+
+func (r *memoryCRUD[T]) List(ctx context.Context, q listing.Query) ([]T, int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	items, total := listing.Slice(r.table.live(), r.spec, q)
+	return items, total, nil
+}
+
+func (r *memoryCRUD[T]) Get(ctx context.Context, id uint) (*T, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	row := r.table.find(id)
+	if row == nil {
+		return nil, ErrNotFound
+	}
+	return clone(row), nil
+}
+
+func (r *memoryCRUD[T]) Create(ctx context.Context, item *T) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	*versionOf(item) = 1
+	r.table.insert(item)
+	return nil
+}
+
+func (r *memoryCRUD[T]) Update(ctx context.Context, item *T) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if err := r.checkVersion(modelOf(item).ID, *versionOf(item)); err != nil {
+		return err
+	}
+	*versionOf(item)++
+	return r.table.update(item)
+}
+
+func (r *memoryCRUD[T]) Delete(ctx context.Context, id, version uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if err := r.checkVersion(id, version); err != nil {
+		return err
+	}
+	return r.table.delete(id)
+}
+
+func (r *memoryCRUD[T]) NextPosition(ctx context.Context) (int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.nextPosition(), nil
+}
+
+// nextPosition is the position after the last live row. The caller holds
+// the store's lock.
+func (r *memoryCRUD[T]) nextPosition() int {
+	next := 0
+	for _, row := range r.table.where(nil) {
+		if p := *positionOf(row); p >= next {
+			next = p + 1
+		}
+	}
+	return next
+}
+
+func (r *memoryCRUD[T]) Reorder(ctx context.Context, order []OrderItem) ([]T, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	positions, err := newPositions(r.table.live(), order)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, row := range r.table.where(nil) {
+		m := modelOf(row)
+		if position := positionOf(row); *position != positions[m.ID] {
+			*position = positions[m.ID]
+			*versionOf(row)++
+			m.UpdatedAt = now
+		}
+	}
+
+	items := r.table.live()
+	sort.SliceStable(items, func(i, j int) bool {
+		return *positionOf(&items[i]) < *positionOf(&items[j])
+	})
+	return items, nil
+}
+
+func (r *memoryCRUD[T]) Trash(ctx context.Context, q listing.Query) ([]T, int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	items, total := listing.Slice(r.table.trashed(), r.spec, q)
+	return items, total, nil
+}
+
+func (r *memoryCRUD[T]) GetIncludingTrash(ctx context.Context, id uint) (*T, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	row := r.table.findAny(id)
+	if row == nil {
+		return nil, ErrNotFound
+	}
+	return clone(row), nil
+}
+
+func (r *memoryCRUD[T]) Restore(ctx context.Context, id uint) (*T, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	row := r.table.findAny(id)
+	if row == nil || !modelOf(row).DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	// The old position may belong to another row by now, so a restored
+	// row goes last
+	*positionOf(row) = r.nextPosition()
+	modelOf(row).DeletedAt = gorm.DeletedAt{}
+	modelOf(row).UpdatedAt = time.Now()
+	*versionOf(row)++
+	return clone(row), nil
+}
+
+func (r *memoryCRUD[T]) Purge(ctx context.Context, id, version uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	row := r.table.findAny(id)
+	if row == nil {
+		return ErrNotFound
+	}
+	if *versionOf(row) != version {
+		return ErrVersionMismatch
+	}
+	r.table.purge(func(item *T) bool { return item == row })
+	return nil
+}
+
+func (r *memoryCRUD[T]) PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return r.table.purge(func(item *T) bool {
+		deleted := modelOf(item).DeletedAt
+		return deleted.Valid && deleted.Time.Before(cutoff)
+	}), nil
+}
+
+// checkVersion fails unless the live row with the given id has version.
+// Callers hold the store lock.
+func (r *memoryCRUD[T]) checkVersion(id, version uint) error {
+	row := r.table.find(id)
+	if row == nil {
+		return ErrNotFound
+	}
+	if *versionOf(row) != version {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+func (r *memoryCRUD[T]) Count(ctx context.Context) (int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return int64(len(r.table.where(nil))), nil
+}
+
+// first returns a copy of the first live row for which match returns true.
+func (r *memoryCRUD[T]) first(match func(*T) bool) (*T, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	rows := r.table.where(match)
+	if len(rows) == 0 {
+		return nil, ErrNotFound
+	}
+	return clone(rows[0]), nil
+}
+
+type memoryProjects struct {
+	memoryCRUD[models.Project]
+}
+
+func (r *memoryProjects) FindByTitle(ctx context.Context, title string) (*models.Project, error) {
+	return r.first(func(p *models.Project) bool { return p.Title == title })
+}
+
+type memoryExperiences struct {
+	memoryCRUD[models.Experience]
+}
+
+func (r *memoryExperiences) FindByTitleAndCompany(ctx context.Context, title, company string) (*models.Experience, error) {
+	return r.first(func(e *models.Experience) bool { return e.Title == title && e.Company == company })
+}
+
+type memorySkillCategories struct {
+	memoryCRUD[models.SkillCategory]
+}
+
+func (r *memorySkillCategories) FindByTitle(ctx context.Context, title string) (*models.SkillCategory, error) {
+	return r.first(func(s *models.SkillCategory) bool { return s.Title == title })
+}
+
+// sortByID orders rows by ID, matching the GORM implementations' ORDER BY id.
+func sortByID[T any](items []T) {
+	sort.Slice(items, func(i, j int) bool {
+		return modelOf(&items[i]).ID < modelOf(&items[j]).ID
+	})
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wannn-site-rebuild-api/auth"
+	"wannn-site-rebuild-api/models"
+)
+
+type memoryUsers struct {
+	s *memoryStore
+}
+
+func (r *memoryUsers) List(ctx context.Context) ([]models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	users := r.s.users.live()
+	sortByID(users)
+	return users, nil
+}
+
+func (r *memoryUsers) Get(ctx context.Context, id uint) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	user := r.s.users.find(id)
+	if user == nil {
+		return nil, ErrNotFound
+	}
+	return clone(user), nil
+}
+
+func (r *memoryUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	users := r.s.users.where(func(u *models.User) bool { return u.Email == email })
+	if len(users) == 0 {
+		return nil, ErrNotFound
+	}
+	return clone(users[0]), nil
+}
+
+func (r *memoryUsers) Create(ctx context.Context, user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	// Like the unique index, only live accounts hold their email
+	if len(r.s.users.where(func(u *models.User) bool { return u.Email == user.Email })) > 0 {
+		return ErrDuplicate
+	}
+	r.s.users.insert(user)
+	return nil
+}
+
+func (r *memoryUsers) Update(ctx context.Context, user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	current := r.s.users.find(user.ID)
+	if current == nil {
+		return ErrNotFound
+	}
+	if current.Role == string(auth.RoleOwner) && user.Role != string(auth.RoleOwner) && !r.anotherOwner(user.ID) {
+		return ErrLastOwner
+	}
+	return r.s.users.update(user)
+}
+
+func (r *memoryUsers) Delete(ctx context.Context, id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	user := r.s.users.find(id)
+	if user == nil {
+		return ErrNotFound
+	}
+	if user.Role == string(auth.RoleOwner) && !r.anotherOwner(id) {
+		return ErrLastOwner
+	}
+	now := time.Now()
+	for _, t := range r.s.refreshTokens.where(func(t *models.RefreshToken) bool { return t.UserID == id && t.RevokedAt == nil }) {
+		t.RevokedAt = &now
+	}
+	return r.s.users.delete(id)
+}
+
+func (r *memoryUsers) anotherOwner(userID uint) bool {
+	owners := r.s.users.where(func(u *models.User) bool {
+		return u.Role == string(auth.RoleOwner) && u.ID != userID
+	})
+	return len(owners) > 0
+}
+
+type memoryAPIKeys struct {
+	s *memoryStore
+}
+
+func (r *memoryAPIKeys) Create(ctx context.Context, key *models.APIKey) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, k := range r.s.apiKeys.rows {
+		if k.KeyHash == key.KeyHash {
+			return ErrDuplicate
+		}
+	}
+	if key.Role == "" {
+		key.Role = string(auth.RoleEditor)
+	}
+	r.s.apiKeys.insert(key)
+	return nil
+}
+
+func (r *memoryAPIKeys) List(ctx context.Context) ([]models.APIKey, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	keys := r.s.apiKeys.live()
+	sortByID(keys)
+	return keys, nil
+}
+
+func (r *memoryAPIKeys) FindActive(ctx context.Context, hash string) (*models.APIKey, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	keys := r.s.apiKeys.where(func(k *models.APIKey) bool { return k.KeyHash == hash && k.RevokedAt == nil })
+	if len(keys) == 0 {
+		return nil, ErrNotFound
+	}
+	return clone(keys[0]), nil
+}
+
+func (r *memoryAPIKeys) MarkUsed(ctx context.Context, id uint, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if key := r.s.apiKeys.find(id); key != nil {
+		key.LastUsedAt = &at
+	}
+	return nil
+}
+
+func (r *memoryAPIKeys) Revoke(ctx context.Context, id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	key := r.s.apiKeys.find(id)
+	if key == nil || key.RevokedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	return nil
+}
+
+type memoryRefreshTokens struct {
+	s *memoryStore
+}
+
+func (r *memoryRefreshTokens) Create(ctx context.Context, token *models.RefreshToken) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, t := range r.s.refreshTokens.rows {
+		if t.TokenHash == token.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	r.s.refreshTokens.insert(token)
+	return nil
+}
+
+func (r *memoryRefreshTokens) Consume(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	token := r.byHash(hash)
+	if token == nil {
+		return nil, ErrNotFound
+	}
+	if token.UsedAt != nil || token.RevokedAt != nil {
+		r.revokeFamily(token.FamilyID)
+		return nil, ErrTokenReused
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	return clone(token), nil
+}
+
+func (r *memoryRefreshTokens) RevokeFamily(ctx context.Context, hash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if token := r.byHash(hash); token != nil {
+		r.revokeFamily(token.FamilyID)
+	}
+	return nil
+}
+
+func (r *memoryRefreshTokens) byHash(hash string) *models.RefreshToken {
+	tokens := r.s.refreshTokens.where(func(t *models.RefreshToken) bool { return t.TokenHash == hash })
+	if len(tokens) == 0 {
+		return nil
+	}
+	return tokens[0]
+}
+
+func (r *memoryRefreshTokens) revokeFamily(family string) {
+	now := time.Now()
+	for _, t := range r.s.refreshTokens.where(func(t *models.RefreshToken) bool { return t.FamilyID == family && t.RevokedAt == nil }) {
+		t.RevokedAt = &now
+	}
+}
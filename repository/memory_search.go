@@ -0,0 +1,13 @@
+package repository
+
+import "context"
+
+type memorySearch struct {
+	s *memoryStore
+}
+
+func (r *memorySearch) Search(ctx context.Context, query string, types []string, limit int) ([]SearchResult, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return searchText(query, types, limit, r.s.projects.live(), r.s.experiences.live(), r.s.skillCategories.live()), nil
+}
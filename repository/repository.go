@@ -0,0 +1,255 @@
+// Package repository hides storage behind interfaces so handlers can run
+// against Postgres (NewGORM) or an in-memory store (NewMemory).
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"wannn-site-rebuild-api/listing"
+	"wannn-site-rebuild-api/models"
+)
+
+var (
+	// ErrNotFound is returned when no live row matches. It is GORM's own
+	// sentinel so apperr.FromDB classifies both implementations alike.
+	ErrNotFound = gorm.ErrRecordNotFound
+	// ErrDuplicate is returned when a unique value is already taken.
+	ErrDuplicate = gorm.ErrDuplicatedKey
+	// ErrLastOwner is returned when a change would leave no owner account.
+	ErrLastOwner = errors.New("cannot remove the last owner")
+	// ErrVersionMismatch is returned when a content row changed since the
+	// version the caller read.
+	ErrVersionMismatch = errors.New("record was modified since it was read")
+	// ErrOrderMismatch is returned when a new order does not list every
+	// live item exactly once.
+	ErrOrderMismatch = errors.New("order must list every item exactly once")
+	// ErrTokenReused is returned when a refresh token is presented twice.
+	// The whole token family has been revoked by the time it is returned.
+	ErrTokenReused = errors.New("refresh token reused")
+)
+
+// OrderItem is one entry of a new display order: a content item and the
+// version the caller last read of it.
+type OrderItem struct {
+	ID      uint
+	Version uint
+}
+
+// CRUD is the storage contract shared by the content types.
+type CRUD[T any] interface {
+	// List returns the items matching q, at most q.PerPage+1 of them so the
+	// caller can tell whether another page exists, and the filtered total.
+	List(ctx context.Context, q listing.Query) ([]T, int64, error)
+	Get(ctx context.Context, id uint) (*T, error)
+	Create(ctx context.Context, item *T) error
+	// Update saves item if the stored row still has item's version, and
+	// bumps the version. It fails with ErrVersionMismatch otherwise.
+	Update(ctx context.Context, item *T) error
+	// Delete removes the row if it still has the given version, failing
+	// with ErrVersionMismatch otherwise.
+	Delete(ctx context.Context, id, version uint) error
+	// Count returns the number of live rows.
+	Count(ctx context.Context) (int64, error)
+	// NextPosition returns the position after the last live item.
+	NextPosition(ctx context.Context) (int, error)
+	// Reorder gives the live items their index in order as position, in
+	// one transaction, and returns them in their new order. Items whose
+	// position changes get a new version. order must list every live item
+	// exactly once, or Reorder fails with ErrOrderMismatch, and with the
+	// version the caller read, or it fails with ErrVersionMismatch.
+	Reorder(ctx context.Context, order []OrderItem) ([]T, error)
+
+	// Trash lists soft-deleted items matching q, like List.
+	Trash(ctx context.Context, q listing.Query) ([]T, int64, error)
+	// GetIncludingTrash returns the item whether it is live or deleted.
+	GetIncludingTrash(ctx context.Context, id uint) (*T, error)
+	// Restore takes an item out of the trash, bumps its version and returns
+	// it. Items that are not in the trash yield ErrNotFound.
+	Restore(ctx context.Context, id uint) (*T, error)
+	// Purge permanently removes the item, live or deleted, if it still has
+	// the given version.
+	Purge(ctx context.Context, id, version uint) error
+	// PurgeTrash permanently removes items deleted before cutoff and
+	// returns how many there were.
+	PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type ProjectRepository interface {
+	CRUD[models.Project]
+	// FindByTitle returns the live project with exactly this title.
+	FindByTitle(ctx context.Context, title string) (*models.Project, error)
+}
+
+type ExperienceRepository interface {
+	CRUD[models.Experience]
+	// FindByTitleAndCompany returns the live experience with exactly this
+	// title at this company.
+	FindByTitleAndCompany(ctx context.Context, title, company string) (*models.Experience, error)
+}
+
+type SkillCategoryRepository interface {
+	CRUD[models.SkillCategory]
+	// FindByTitle returns the live skill category with exactly this title.
+	FindByTitle(ctx context.Context, title string) (*models.SkillCategory, error)
+}
+
+type UserRepository interface {
+	List(ctx context.Context) ([]models.User, error)
+	Get(ctx context.Context, id uint) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	// Update saves user, failing with ErrLastOwner if it demotes the only
+	// owner.
+	Update(ctx context.Context, user *models.User) error
+	// Delete removes a user and revokes their refresh tokens, failing with
+	// ErrLastOwner for the only owner.
+	Delete(ctx context.Context, id uint) error
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	List(ctx context.Context) ([]models.APIKey, error)
+	// FindActive returns the unrevoked key with the given hash.
+	FindActive(ctx context.Context, hash string) (*models.APIKey, error)
+	MarkUsed(ctx context.Context, id uint, at time.Time) error
+	// Revoke revokes an active key, or returns ErrNotFound.
+	Revoke(ctx context.Context, id uint) error
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	// Consume atomically marks the token with the given hash as used and
+	// returns it. Unknown tokens yield ErrNotFound; tokens already used or
+	// revoked yield ErrTokenReused after their family is revoked.
+	Consume(ctx context.Context, hash string) (*models.RefreshToken, error)
+	// RevokeFamily revokes every token issued from the same login as the
+	// token with the given hash. Unknown tokens are ignored.
+	RevokeFamily(ctx context.Context, hash string) error
+}
+
+// SearchResult is one ranked hit. Snippet is HTML: escaped text with the
+// matched terms wrapped in <mark></mark>. Title is plain text.
+type SearchResult struct {
+	Type    string  `json:"type"`
+	ID      uint    `json:"id"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
+
+type SearchRepository interface {
+	// Search returns up to limit hits of the given types ("project",
+	// "experience", "skill"), best match first.
+	Search(ctx context.Context, query string, types []string, limit int) ([]SearchResult, error)
+}
+
+// Repositories bundles every repository the API needs.
+type Repositories struct {
+	Projects        ProjectRepository
+	Experiences     ExperienceRepository
+	SkillCategories SkillCategoryRepository
+	Users           UserRepository
+	APIKeys         APIKeyRepository
+	RefreshTokens   RefreshTokenRepository
+	Search          SearchRepository
+}
+
+// versionOf returns the optimistic concurrency version of a content item.
+func versionOf[T any](item *T) *uint {
+	return reflect.ValueOf(item).Elem().FieldByName("Version").Addr().Interface().(*uint)
+}
+
+// positionOf returns the display position of a content item.
+func positionOf[T any](item *T) *int {
+	return reflect.ValueOf(item).Elem().FieldByName("Position").Addr().Interface().(*int)
+}
+
+// newPositions maps the ID of every item to its index in order. It fails
+// with ErrOrderMismatch unless order lists each item exactly once, and
+// with ErrVersionMismatch when an item's version is not the listed one.
+func newPositions[T any](items []T, order []OrderItem) (map[uint]int, error) {
+	positions := make(map[uint]int, len(order))
+	versions := make(map[uint]uint, len(order))
+	for i, o := range order {
+		if _, dup := positions[o.ID]; dup {
+			return nil, ErrOrderMismatch
+		}
+		positions[o.ID] = i
+		versions[o.ID] = o.Version
+	}
+	if len(positions) != len(items) {
+		return nil, ErrOrderMismatch
+	}
+	for i := range items {
+		if _, ok := positions[modelOf(&items[i]).ID]; !ok {
+			return nil, ErrOrderMismatch
+		}
+	}
+	for i := range items {
+		if *versionOf(&items[i]) != versions[modelOf(&items[i]).ID] {
+			return nil, ErrVersionMismatch
+		}
+	}
+	return positions, nil
+}
+
+var (
+	positionField  = listing.Field{Column: "position", GoName: "Position", Kind: listing.Int}
+	createdAtField = listing.Field{Column: "created_at", GoName: "CreatedAt", Kind: listing.Time}
+	updatedAtField = listing.Field{Column: "updated_at", GoName: "UpdatedAt", Kind: listing.Time}
+	titleField     = listing.Field{Column: "title", GoName: "Title", Kind: listing.String}
+	titleFilter    = listing.Filter{Column: "title", GoName: "Title", Match: listing.Contains}
+	// byPosition lists content in the order the owner chose
+	byPosition = []listing.Sort{{Field: "position"}}
+)
+
+// ProjectListing declares how projects can be sorted and filtered.
+var ProjectListing = listing.Spec{
+	Sortable: map[string]listing.Field{
+		"position":   positionField,
+		"created_at": createdAtField,
+		"updated_at": updatedAtField,
+		"title":      titleField,
+	},
+	Filters: map[string]listing.Filter{
+		"title":      titleFilter,
+		"technology": {Column: "technologies", GoName: "Technologies", Match: listing.HasElement},
+	},
+	Default: byPosition,
+}
+
+// ExperienceListing declares how experiences can be sorted and filtered.
+var ExperienceListing = listing.Spec{
+	Sortable: map[string]listing.Field{
+		"position":   positionField,
+		"created_at": createdAtField,
+		"updated_at": updatedAtField,
+		"title":      titleField,
+		"company":    {Column: "company", GoName: "Company", Kind: listing.String},
+	},
+	Filters: map[string]listing.Filter{
+		"title":   titleFilter,
+		"company": {Column: "company", GoName: "Company", Match: listing.Equal},
+	},
+	Default: byPosition,
+}
+
+// SkillCategoryListing declares how skill categories can be sorted and
+// filtered.
+var SkillCategoryListing = listing.Spec{
+	Sortable: map[string]listing.Field{
+		"position":   positionField,
+		"created_at": createdAtField,
+		"updated_at": updatedAtField,
+		"title":      titleField,
+	},
+	Filters: map[string]listing.Filter{
+		"title": titleFilter,
+		"skill": {Column: "skills", GoName: "Skills", Match: listing.HasElement},
+	},
+	Default: byPosition,
+}
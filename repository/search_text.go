@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"wannn-site-rebuild-api/models"
+)
+
+// searchWeights mirror the tsvector weights of migration 6.
+const (
+	weightA = 1.0
+	weightB = 0.4
+	weightC = 0.2
+)
+
+// searchDoc is one candidate hit with its weighted text.
+type searchDoc struct {
+	result SearchResult
+	fields []weightedText
+	body   string
+}
+
+type weightedText struct {
+	text   string
+	weight float64
+}
+
+// searchText approximates the Postgres full-text search for backends
+// without it: every query word must prefix a word of the document, matches
+// in heavier fields rank higher, and the snippet is escaped HTML with
+// matched words wrapped in <mark></mark>.
+func searchText(query string, types []string, limit int, projects []models.Project, experiences []models.Experience, skills []models.SkillCategory) []SearchResult {
+	terms := searchTerms(query)
+	results := []SearchResult{}
+	if len(terms) == 0 {
+		return results
+	}
+
+	allowed := make(map[string]bool, len(types))
+	for _, t := range types {
+		allowed[t] = true
+	}
+
+	var docs []searchDoc
+	if allowed["project"] {
+		for _, p := range projects {
+			tech := strings.Join(p.Technologies, " ")
+			docs = append(docs, searchDoc{
+				result: SearchResult{Type: "project", ID: p.ID, Title: p.Title},
+				fields: []weightedText{{p.Title, weightA}, {tech, weightB}, {p.Description, weightC}},
+				body:   p.Description + " " + tech,
+			})
+		}
+	}
+	if allowed["experience"] {
+		for _, e := range experiences {
+			desc := strings.Join(e.Description, " · ")
+			docs = append(docs, searchDoc{
+				result: SearchResult{Type: "experience", ID: e.ID, Title: e.Title},
+				fields: []weightedText{{e.Title, weightA}, {e.Company, weightA}, {desc, weightC}},
+				body:   e.Company + ": " + desc,
+			})
+		}
+	}
+	if allowed["skill"] {
+		for _, s := range skills {
+			joined := strings.Join(s.Skills, ", ")
+			docs = append(docs, searchDoc{
+				result: SearchResult{Type: "skill", ID: s.ID, Title: s.Title},
+				fields: []weightedText{{joined, weightA}, {s.Title, weightB}},
+				body:   joined,
+			})
+		}
+	}
+
+	for _, doc := range docs {
+		rank, ok := rankDoc(doc.fields, terms)
+		if !ok {
+			continue
+		}
+		doc.result.Rank = rank
+		doc.result.Snippet = highlight(doc.body, terms)
+		results = append(results, doc.result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// searchTerms lower-cases the query and splits it into words, dropping
+// websearch syntax such as quotes and the "or" keyword.
+func searchTerms(query string) []string {
+	var terms []string
+	for _, w := range splitWords(strings.ToLower(query)) {
+		if w != "or" {
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+func splitWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func termMatches(word string, terms []string) bool {
+	word = strings.ToLower(word)
+	for _, t := range terms {
+		if strings.HasPrefix(word, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// rankDoc sums the weights of every field word matching a term. It reports
+// false unless each term matched somewhere.
+func rankDoc(fields []weightedText, terms []string) (float64, bool) {
+	rank := 0.0
+	for _, t := range terms {
+		found := false
+		for _, f := range fields {
+			for _, w := range splitWords(strings.ToLower(f.text)) {
+				if strings.HasPrefix(w, t) {
+					rank += f.weight
+					found = true
+				}
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return rank, true
+}
+
+// snippetWords is the length of a snippet, matching MaxWords of the
+// Postgres ts_headline options.
+const snippetWords = 30
+
+// highlight cuts a window of snippetWords words out of text, starting
+// shortly before the first match, HTML-escapes it and wraps the words that
+// match a term in <mark></mark>. The marks are the only markup in the
+// result, so stored content can never inject any.
+func highlight(text string, terms []string) string {
+	type span struct{ start, end int }
+	var words []span
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if inWord && start < 0 {
+			start = i
+		} else if !inWord && start >= 0 {
+			words = append(words, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, span{start, len(text)})
+	}
+	if len(words) == 0 {
+		return html.EscapeString(text)
+	}
+
+	first := 0
+	for i, w := range words {
+		if termMatches(text[w.start:w.end], terms) {
+			first = i
+			break
+		}
+	}
+	from := first - 5
+	if from < 0 || len(words) <= snippetWords {
+		from = 0
+	}
+	to := from + snippetWords
+	if to > len(words) {
+		to = len(words)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("… ")
+	}
+	pos := 0
+	if from > 0 {
+		pos = words[from].start
+	}
+	for _, w := range words[from:to] {
+		b.WriteString(html.EscapeString(text[pos:w.start]))
+		word := text[w.start:w.end]
+		if termMatches(word, terms) {
+			b.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+		pos = w.end
+	}
+	if to < len(words) {
+		b.WriteString(" …")
+	} else {
+		b.WriteString(html.EscapeString(text[pos:]))
+	}
+	return b.String()
+}
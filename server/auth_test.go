@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"wannn-site-rebuild-api/auth"
+	"wannn-site-rebuild-api/models"
+)
+
+// testPassword is the password of accounts made by createUser.
+const testPassword = "correct horse battery"
+
+// createUser stores an account with testPassword and returns it.
+func (s *testServer) createUser(email string, role auth.Role) *models.User {
+	s.t.Helper()
+	hash, err := auth.HashPassword(testPassword)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	user := &models.User{Email: email, PasswordHash: hash, Role: string(role)}
+	if err := s.repos.Users.Create(context.Background(), user); err != nil {
+		s.t.Fatal(err)
+	}
+	return user
+}
+
+func TestAPIKeyLastUsed(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			ctx := context.Background()
+			lastUsed := func() *time.Time {
+				t.Helper()
+				keys, err := s.repos.APIKeys.List(ctx)
+				if err != nil || len(keys) != 1 {
+					t.Fatalf("keys %v, err %v", keys, err)
+				}
+				return keys[0].LastUsedAt
+			}
+
+			if lastUsed() != nil {
+				t.Fatal("new key already marked used")
+			}
+			s.do(http.MethodGet, "/projects", "")
+			first := lastUsed()
+			if first == nil {
+				t.Fatal("first request did not mark the key used")
+			}
+
+			s.do(http.MethodGet, "/projects", "")
+			if got := lastUsed(); got == nil || !got.Equal(*first) {
+				t.Errorf("last used %v after a second request, want %v kept", got, first)
+			}
+
+			keys, _ := s.repos.APIKeys.List(ctx)
+			stale := time.Now().Add(-2 * time.Minute)
+			if err := s.repos.APIKeys.MarkUsed(ctx, keys[0].ID, stale); err != nil {
+				t.Fatal(err)
+			}
+			s.do(http.MethodGet, "/projects", "")
+			if got := lastUsed(); got == nil || !got.After(stale) {
+				t.Errorf("last used %v, want it refreshed after %v", got, stale)
+			}
+		})
+	}
+}
+
+func TestLoginRefreshLogout(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			s.createUser("owner@example.com", auth.RoleOwner)
+
+			login := func(email, password string) (int, map[string]interface{}) {
+				return s.send(http.MethodPost, "/auth/login", `{"email":"`+email+`","password":"`+password+`"}`, "")
+			}
+			refresh := func(token interface{}) (int, map[string]interface{}) {
+				return s.send(http.MethodPost, "/auth/refresh", `{"refresh_token":"`+token.(string)+`"}`, "")
+			}
+			canWrite := func(access interface{}) int {
+				status, _ := s.sendWith(http.MethodPost, "/projects", `{"title":"T","description":"d","technologies":["Go"]}`, "",
+					map[string]string{"Authorization": "Bearer " + access.(string)})
+				return status
+			}
+
+			if status, _ := login("owner@example.com", "wrong password!"); status != http.StatusUnauthorized {
+				t.Errorf("wrong password: status %d, want 401", status)
+			}
+			if status, _ := login("nobody@example.com", testPassword); status != http.StatusUnauthorized {
+				t.Errorf("unknown email: status %d, want 401", status)
+			}
+
+			// Emails are matched case-insensitively
+			status, first := login(" Owner@Example.com ", testPassword)
+			if status != http.StatusOK || first["token_type"] != "Bearer" {
+				t.Fatalf("login: status %d, body %v", status, first)
+			}
+			if status := canWrite(first["access_token"]); status != http.StatusCreated {
+				t.Errorf("write with the access token: status %d, want 201", status)
+			}
+
+			// Each refresh hands out a new pair and uses up the old token
+			status, second := refresh(first["refresh_token"])
+			if status != http.StatusOK || second["refresh_token"] == first["refresh_token"] {
+				t.Fatalf("refresh: status %d, body %v", status, second)
+			}
+			status, third := refresh(second["refresh_token"])
+			if status != http.StatusOK {
+				t.Fatalf("second refresh: status %d, body %v", status, third)
+			}
+
+			// A separate login is a separate family and survives the reuse
+			_, other := login("owner@example.com", testPassword)
+
+			// Replaying a used token revokes every token of its family,
+			// including the live descendant
+			if status, _ := refresh(first["refresh_token"]); status != http.StatusUnauthorized {
+				t.Errorf("replayed token: status %d, want 401", status)
+			}
+			if status, _ := refresh(third["refresh_token"]); status != http.StatusUnauthorized {
+				t.Errorf("descendant after reuse: status %d, want 401", status)
+			}
+			if status, _ := refresh(second["refresh_token"]); status != http.StatusUnauthorized {
+				t.Errorf("used descendant after reuse: status %d, want 401", status)
+			}
+
+			status, rotated := refresh(other["refresh_token"])
+			if status != http.StatusOK {
+				t.Fatalf("other session after reuse: status %d, body %v", status, rotated)
+			}
+
+			// Logout ends the session: the token and its family are dead
+			if status, _ := s.send(http.MethodPost, "/auth/logout", `{"refresh_token":"`+rotated["refresh_token"].(string)+`"}`, ""); status != http.StatusNoContent {
+				t.Fatalf("logout: status %d, want 204", status)
+			}
+			if status, _ := refresh(rotated["refresh_token"]); status != http.StatusUnauthorized {
+				t.Errorf("refresh after logout: status %d, want 401", status)
+			}
+			if status, _ := refresh(other["refresh_token"]); status != http.StatusUnauthorized {
+				t.Errorf("used token of a logged out family: status %d, want 401", status)
+			}
+
+			if status, _ := refresh("wxr_unknown"); status != http.StatusUnauthorized {
+				t.Errorf("unknown token: status %d, want 401", status)
+			}
+		})
+	}
+}
+
+func TestCreateUserDuplicateEmail(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			body := `{"email":"editor@example.com","password":"` + testPassword + `","role":"editor"}`
+			status, created := s.do(http.MethodPost, "/users", body)
+			if status != http.StatusCreated {
+				t.Fatalf("create: status %d, body %v", status, created)
+			}
+
+			status, got := s.do(http.MethodPost, "/users", `{"email":"Editor@Example.com","password":"`+testPassword+`","role":"viewer"}`)
+			if status != http.StatusConflict || got["detail"] != "A user with this email already exists" {
+				t.Errorf("duplicate: status %d, body %v", status, got)
+			}
+
+			// A deleted account gives its email up
+			id := strconv.Itoa(int(created["id"].(float64)))
+			if status, _ := s.do(http.MethodDelete, "/users/"+id, ""); status != http.StatusNoContent {
+				t.Fatalf("delete: status %d", status)
+			}
+			if status, got := s.do(http.MethodPost, "/users", body); status != http.StatusCreated {
+				t.Errorf("reuse a deleted account's email: status %d, body %v", status, got)
+			}
+		})
+	}
+}
+
+func TestLastOwner(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			first := s.createUser("first@example.com", auth.RoleOwner)
+			second := s.createUser("second@example.com", auth.RoleOwner)
+			path := func(u *models.User) string { return "/users/" + strconv.Itoa(int(u.ID)) }
+
+			if status, body := s.do(http.MethodPut, path(first), `{"role":"editor"}`); status != http.StatusOK {
+				t.Fatalf("demote one of two owners: status %d, body %v", status, body)
+			}
+			if status, body := s.do(http.MethodPut, path(second), `{"role":"editor"}`); status != http.StatusConflict {
+				t.Errorf("demote the last owner: status %d, body %v", status, body)
+			}
+			if status, body := s.do(http.MethodDelete, path(second), ""); status != http.StatusConflict {
+				t.Errorf("delete the last owner: status %d, body %v", status, body)
+			}
+			if status, body := s.do(http.MethodDelete, path(first), ""); status != http.StatusNoContent {
+				t.Errorf("delete an editor: status %d, body %v", status, body)
+			}
+		})
+	}
+}
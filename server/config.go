@@ -0,0 +1,561 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"wannn-site-rebuild-api/config"
+	"wannn-site-rebuild-api/logging"
+	"wannn-site-rebuild-api/middleware"
+	"wannn-site-rebuild-api/ratelimit"
+	"wannn-site-rebuild-api/tracing"
+)
+
+// headerXRealIP is the client IP header set by the sample nginx config.
+const headerXRealIP = "X-Real-IP"
+
+// Config is the typed server configuration. Load fills it from defaults, an
+// optional JSON file, the environment and command line flags, each layer
+// overriding the previous one.
+type Config struct {
+	Port         int
+	CORS         middleware.CORSConfig
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownTimeout is how long in-flight requests may run after a
+	// shutdown signal before they are cut off.
+	ShutdownTimeout time.Duration
+	// RefreshTokenTTL is how long a refresh token stays valid.
+	RefreshTokenTTL time.Duration
+	// TrashRetention is how long deleted content stays restorable before
+	// it is purged for good. Zero keeps it forever.
+	TrashRetention time.Duration
+	// LogLevel is the minimum level logged; LogFormat is json or text.
+	LogLevel  slog.Level
+	LogFormat string
+	Tracing   tracing.Config
+	// TrustedProxies are the proxy IPs and CIDR ranges whose ProxyHeader
+	// is believed for the client IP; other peers are taken at face value.
+	TrustedProxies []string
+	ProxyHeader    string
+	RateLimit      ratelimit.Config
+	Database       config.DatabaseConfig
+}
+
+// DefaultConfig returns the configuration used when nothing overrides it.
+func DefaultConfig() Config {
+	return Config{
+		Port: 3000,
+		CORS: middleware.CORSConfig{
+			Origins:      []string{"*"},
+			WriteMethods: []string{fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete},
+			Headers:      []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Request-ID", fiber.HeaderIfMatch, fiber.HeaderIfNoneMatch},
+			MaxAge:       10 * time.Minute,
+		},
+		ReadTimeout:     10 * time.Second,
+		WriteTimeout:    10 * time.Second,
+		IdleTimeout:     60 * time.Second,
+		ShutdownTimeout: 15 * time.Second,
+		RefreshTokenTTL: 30 * 24 * time.Hour,
+		TrashRetention:  30 * 24 * time.Hour,
+		LogLevel:        slog.LevelInfo,
+		LogFormat:       logging.FormatJSON,
+		Tracing: tracing.Config{
+			Exporter:    tracing.ExporterNone,
+			SampleRatio: 1,
+		},
+		TrustedProxies: []string{"127.0.0.1", "::1"},
+		ProxyHeader:    headerXRealIP,
+		RateLimit: ratelimit.Config{
+			Store:  ratelimit.StoreMemory,
+			Window: time.Minute,
+			Reads:  300,
+			Writes: 60,
+		},
+		Database: config.DatabaseConfig{
+			Driver:  config.DriverPostgres,
+			SSLMode: "require",
+			Path:    "wandhx.db",
+		},
+	}
+}
+
+// fileConfig is the JSON config file layout. Durations are strings such as
+// "10s"; absent keys keep their current value.
+type fileConfig struct {
+	Port                 *int                   `json:"port"`
+	CORSOrigins          []string               `json:"cors_origins"`
+	CORSWriteOrigins     []string               `json:"cors_write_origins"`
+	CORSWriteMethods     []string               `json:"cors_write_methods"`
+	CORSHeaders          []string               `json:"cors_headers"`
+	CORSAllowCredentials *bool                  `json:"cors_allow_credentials"`
+	CORSMaxAge           *string                `json:"cors_max_age"`
+	ReadTimeout          *string                `json:"read_timeout"`
+	WriteTimeout         *string                `json:"write_timeout"`
+	IdleTimeout          *string                `json:"idle_timeout"`
+	ShutdownTimeout      *string                `json:"shutdown_timeout"`
+	RefreshTokenTTL      *string                `json:"refresh_token_ttl"`
+	TrashRetention       *string                `json:"trash_retention"`
+	LogLevel             *string                `json:"log_level"`
+	LogFormat            *string                `json:"log_format"`
+	Tracing              *fileTracing           `json:"tracing"`
+	TrustedProxies       []string               `json:"trusted_proxies"`
+	ProxyHeader          *string                `json:"proxy_header"`
+	RateLimit            *fileRateLimit         `json:"rate_limit"`
+	Database             *config.DatabaseConfig `json:"database"`
+}
+
+type fileTracing struct {
+	Exporter    string   `json:"exporter"`
+	Endpoint    string   `json:"endpoint"`
+	SampleRatio *float64 `json:"sample_ratio"`
+}
+
+type fileRateLimit struct {
+	Store    string  `json:"store"`
+	RedisURL string  `json:"redis_url"`
+	Window   *string `json:"window"`
+	Reads    *int    `json:"reads"`
+	Writes   *int    `json:"writes"`
+}
+
+// Load builds the configuration from defaults, the JSON file named by the
+// -config flag or CONFIG_FILE, the environment and the flags in args, then
+// validates it.
+func Load(args []string) (Config, error) {
+	cfg := DefaultConfig()
+
+	fs := flag.NewFlagSet("wandhx-be", flag.ContinueOnError)
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "JSON config file")
+	port := fs.Int("port", 0, "port to listen on")
+	origins := fs.String("cors-origins", "", "comma separated origins allowed to read through CORS")
+	writeOrigins := fs.String("cors-write-origins", "", "comma separated origins allowed to write through CORS")
+	readTimeout := fs.Duration("read-timeout", 0, "maximum time to read a request")
+	writeTimeout := fs.Duration("write-timeout", 0, "maximum time to write a response")
+	idleTimeout := fs.Duration("idle-timeout", 0, "maximum time to keep an idle connection open")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 0, "maximum time to drain in-flight requests on shutdown")
+	trashRetention := fs.Duration("trash-retention", 0, "how long deleted content can be restored before it is purged (0 keeps it)")
+	logLevel := fs.String("log-level", "", "minimum log level: debug, info, warn or error")
+	logFormat := fs.String("log-format", "", "log output format: json or text")
+	tracingExporter := fs.String("tracing-exporter", "", "trace exporter: none, otlp or stdout")
+	tracingEndpoint := fs.String("tracing-endpoint", "", "OTLP/HTTP collector URL")
+	trustedProxies := fs.String("trusted-proxies", "", "comma separated proxy IPs and CIDR ranges trusted to report the client IP")
+	rateLimitStore := fs.String("rate-limit-store", "", "rate limit counter store: memory or redis")
+	driver := fs.String("db-driver", "", "storage driver: postgres, sqlite or memory")
+	dbPath := fs.String("db-path", "", "SQLite database file")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *file != "" {
+		if err := cfg.applyFile(*file); err != nil {
+			return cfg, err
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+
+	// Only flags given explicitly override the layers below
+	var levelErr error
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Port = *port
+		case "cors-origins":
+			cfg.CORS.Origins = splitList(*origins)
+		case "cors-write-origins":
+			cfg.CORS.WriteOrigins = splitList(*writeOrigins)
+		case "read-timeout":
+			cfg.ReadTimeout = *readTimeout
+		case "write-timeout":
+			cfg.WriteTimeout = *writeTimeout
+		case "idle-timeout":
+			cfg.IdleTimeout = *idleTimeout
+		case "shutdown-timeout":
+			cfg.ShutdownTimeout = *shutdownTimeout
+		case "trash-retention":
+			cfg.TrashRetention = *trashRetention
+		case "log-level":
+			levelErr = parseLevel(&cfg.LogLevel, "-log-level", *logLevel)
+		case "log-format":
+			cfg.LogFormat = *logFormat
+		case "tracing-exporter":
+			cfg.Tracing.Exporter = *tracingExporter
+		case "tracing-endpoint":
+			cfg.Tracing.Endpoint = *tracingEndpoint
+		case "trusted-proxies":
+			cfg.TrustedProxies = splitList(*trustedProxies)
+		case "rate-limit-store":
+			cfg.RateLimit.Store = *rateLimitStore
+		case "db-driver":
+			cfg.Database.Driver = *driver
+		case "db-path":
+			cfg.Database.Path = *dbPath
+		}
+	})
+	if levelErr != nil {
+		return cfg, levelErr
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func (c *Config) applyFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	var f fileConfig
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	if f.Port != nil {
+		c.Port = *f.Port
+	}
+	lists := []struct {
+		value []string
+		dest  *[]string
+	}{
+		{f.CORSOrigins, &c.CORS.Origins},
+		{f.CORSWriteOrigins, &c.CORS.WriteOrigins},
+		{f.CORSWriteMethods, &c.CORS.WriteMethods},
+		{f.CORSHeaders, &c.CORS.Headers},
+	}
+	for _, l := range lists {
+		if l.value != nil {
+			*l.dest = l.value
+		}
+	}
+	if f.CORSAllowCredentials != nil {
+		c.CORS.AllowCredentials = *f.CORSAllowCredentials
+	}
+	durations := []struct {
+		name  string
+		value *string
+		dest  *time.Duration
+	}{
+		{"read_timeout", f.ReadTimeout, &c.ReadTimeout},
+		{"write_timeout", f.WriteTimeout, &c.WriteTimeout},
+		{"idle_timeout", f.IdleTimeout, &c.IdleTimeout},
+		{"shutdown_timeout", f.ShutdownTimeout, &c.ShutdownTimeout},
+		{"cors_max_age", f.CORSMaxAge, &c.CORS.MaxAge},
+		{"refresh_token_ttl", f.RefreshTokenTTL, &c.RefreshTokenTTL},
+		{"trash_retention", f.TrashRetention, &c.TrashRetention},
+	}
+
+	for _, d := range durations {
+		if d.value == nil {
+			continue
+		}
+		v, err := time.ParseDuration(*d.value)
+		if err != nil {
+			return fmt.Errorf("config file %s: invalid %s %q", path, d.name, *d.value)
+		}
+		*d.dest = v
+	}
+	if f.LogLevel != nil {
+		if err := parseLevel(&c.LogLevel, "log_level", *f.LogLevel); err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+	if f.LogFormat != nil {
+		c.LogFormat = *f.LogFormat
+	}
+	if f.Tracing != nil {
+		// Keep defaults for tracing keys the file leaves out
+		mergeString(&c.Tracing.Exporter, f.Tracing.Exporter)
+		mergeString(&c.Tracing.Endpoint, f.Tracing.Endpoint)
+		if f.Tracing.SampleRatio != nil {
+			c.Tracing.SampleRatio = *f.Tracing.SampleRatio
+		}
+	}
+	if f.TrustedProxies != nil {
+		c.TrustedProxies = f.TrustedProxies
+	}
+	if f.ProxyHeader != nil {
+		c.ProxyHeader = *f.ProxyHeader
+	}
+	if f.RateLimit != nil {
+		mergeString(&c.RateLimit.Store, f.RateLimit.Store)
+		mergeString(&c.RateLimit.RedisURL, f.RateLimit.RedisURL)
+		if v := f.RateLimit.Window; v != nil {
+			window, err := time.ParseDuration(*v)
+			if err != nil {
+				return fmt.Errorf("config file %s: invalid rate_limit.window %q", path, *v)
+			}
+			c.RateLimit.Window = window
+		}
+		if f.RateLimit.Reads != nil {
+			c.RateLimit.Reads = *f.RateLimit.Reads
+		}
+		if f.RateLimit.Writes != nil {
+			c.RateLimit.Writes = *f.RateLimit.Writes
+		}
+	}
+	if f.Database != nil {
+		db := *f.Database
+		// Keep defaults for database keys the file leaves out
+		mergeString(&c.Database.Driver, db.Driver)
+		mergeString(&c.Database.Host, db.Host)
+		mergeString(&c.Database.Port, db.Port)
+		mergeString(&c.Database.User, db.User)
+		mergeString(&c.Database.Password, db.Password)
+		mergeString(&c.Database.Name, db.Name)
+		mergeString(&c.Database.SSLMode, db.SSLMode)
+		mergeString(&c.Database.Path, db.Path)
+	}
+	return nil
+}
+
+func (c *Config) applyEnv() error {
+	if v := os.Getenv("PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid PORT %q", v)
+		}
+		c.Port = port
+	}
+	lists := []struct {
+		env  string
+		dest *[]string
+	}{
+		{"CORS_ORIGINS", &c.CORS.Origins},
+		{"CORS_WRITE_ORIGINS", &c.CORS.WriteOrigins},
+		{"CORS_WRITE_METHODS", &c.CORS.WriteMethods},
+		{"CORS_HEADERS", &c.CORS.Headers},
+	}
+	for _, l := range lists {
+		if v := os.Getenv(l.env); v != "" {
+			*l.dest = splitList(v)
+		}
+	}
+	if v := os.Getenv("CORS_ALLOW_CREDENTIALS"); v != "" {
+		allow, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid CORS_ALLOW_CREDENTIALS %q", v)
+		}
+		c.CORS.AllowCredentials = allow
+	}
+	durations := []struct {
+		env  string
+		dest *time.Duration
+	}{
+		{"READ_TIMEOUT", &c.ReadTimeout},
+		{"WRITE_TIMEOUT", &c.WriteTimeout},
+		{"IDLE_TIMEOUT", &c.IdleTimeout},
+		{"SHUTDOWN_TIMEOUT", &c.ShutdownTimeout},
+		{"CORS_MAX_AGE", &c.CORS.MaxAge},
+		{"REFRESH_TOKEN_TTL", &c.RefreshTokenTTL},
+		{"TRASH_RETENTION", &c.TrashRetention},
+		{"RATE_LIMIT_WINDOW", &c.RateLimit.Window},
+	}
+	for _, d := range durations {
+		v := os.Getenv(d.env)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", d.env, v)
+		}
+		*d.dest = parsed
+	}
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if err := parseLevel(&c.LogLevel, "LOG_LEVEL", v); err != nil {
+			return err
+		}
+	}
+	mergeString(&c.LogFormat, os.Getenv("LOG_FORMAT"))
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		c.TrustedProxies = splitList(v)
+	}
+	mergeString(&c.ProxyHeader, os.Getenv("PROXY_HEADER"))
+	mergeString(&c.RateLimit.Store, os.Getenv("RATE_LIMIT_STORE"))
+	mergeString(&c.RateLimit.RedisURL, os.Getenv("RATE_LIMIT_REDIS_URL"))
+	budgets := []struct {
+		env  string
+		dest *int
+	}{
+		{"RATE_LIMIT_READS", &c.RateLimit.Reads},
+		{"RATE_LIMIT_WRITES", &c.RateLimit.Writes},
+	}
+	for _, b := range budgets {
+		v := os.Getenv(b.env)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", b.env, v)
+		}
+		*b.dest = n
+	}
+	mergeString(&c.Tracing.Exporter, os.Getenv("TRACING_EXPORTER"))
+	mergeString(&c.Tracing.Endpoint, os.Getenv("TRACING_ENDPOINT"))
+	if v := os.Getenv("TRACING_SAMPLE_RATIO"); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid TRACING_SAMPLE_RATIO %q", v)
+		}
+		c.Tracing.SampleRatio = ratio
+	}
+	mergeString(&c.Database.Driver, os.Getenv("DB_DRIVER"))
+	mergeString(&c.Database.Host, os.Getenv("DB_HOST"))
+	mergeString(&c.Database.Port, os.Getenv("DB_PORT"))
+	mergeString(&c.Database.User, os.Getenv("DB_USER"))
+	mergeString(&c.Database.Password, os.Getenv("DB_PASSWORD"))
+	mergeString(&c.Database.Name, os.Getenv("DB_NAME"))
+	mergeString(&c.Database.SSLMode, os.Getenv("DB_SSLMODE"))
+	mergeString(&c.Database.Path, os.Getenv("DB_PATH"))
+	return nil
+}
+
+// Validate reports every problem with the configuration at once.
+func (c Config) Validate() error {
+	var errs []error
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port must be between 1 and 65535, got %d", c.Port))
+	}
+	if c.ReadTimeout < 0 || c.WriteTimeout < 0 || c.IdleTimeout < 0 || c.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("timeouts must not be negative"))
+	}
+	if c.RefreshTokenTTL <= 0 {
+		errs = append(errs, errors.New("refresh token TTL must be positive"))
+	}
+	if c.TrashRetention < 0 {
+		errs = append(errs, errors.New("trash retention must not be negative"))
+	}
+	if c.LogFormat != logging.FormatJSON && c.LogFormat != logging.FormatText {
+		errs = append(errs, fmt.Errorf("log format must be json or text, got %q", c.LogFormat))
+	}
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		errs = append(errs, fmt.Errorf("unknown tracing exporter %q (expected none, otlp or stdout)", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing sample ratio must be between 0 and 1, got %g", c.Tracing.SampleRatio))
+	}
+	if c.Tracing.Endpoint != "" {
+		u, err := url.Parse(c.Tracing.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("tracing endpoint %q must be an http or https URL", c.Tracing.Endpoint))
+		}
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("trusted proxy %q must be an IP address or CIDR range", proxy))
+			}
+		}
+	}
+	if c.ProxyHeader != headerXRealIP && c.ProxyHeader != fiber.HeaderXForwardedFor {
+		errs = append(errs, fmt.Errorf("proxy header must be X-Real-IP or X-Forwarded-For, got %q", c.ProxyHeader))
+	}
+	rl := c.RateLimit
+	switch rl.Store {
+	case ratelimit.StoreMemory:
+	case ratelimit.StoreRedis:
+		if u, err := url.Parse(rl.RedisURL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+			errs = append(errs, errors.New("the redis rate limit store requires RATE_LIMIT_REDIS_URL as redis://host:port or rediss://host:port"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown rate limit store %q (expected memory or redis)", rl.Store))
+	}
+	if rl.Window <= 0 {
+		errs = append(errs, errors.New("rate limit window must be positive"))
+	}
+	if rl.Reads < 0 || rl.Writes < 0 {
+		errs = append(errs, errors.New("rate limit budgets must not be negative"))
+	}
+
+	if len(c.CORS.Origins) == 0 {
+		errs = append(errs, errors.New("at least one CORS origin is required"))
+	}
+	for _, origin := range c.CORS.Origins {
+		if origin != "*" && !validOrigin(origin) {
+			errs = append(errs, fmt.Errorf("CORS origin %q must be *, scheme://host[:port] or scheme://*.domain", origin))
+		}
+	}
+	for _, origin := range c.CORS.WriteOrigins {
+		if !validOrigin(origin) {
+			errs = append(errs, fmt.Errorf("CORS write origin %q must be scheme://host[:port] or scheme://*.domain; * is not allowed for writes", origin))
+		}
+	}
+	for _, method := range c.CORS.WriteMethods {
+		switch method {
+		case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+		default:
+			errs = append(errs, fmt.Errorf("CORS write method %q must be POST, PUT, PATCH or DELETE", method))
+		}
+	}
+	if c.CORS.MaxAge < 0 {
+		errs = append(errs, errors.New("CORS max age must not be negative"))
+	}
+
+	db := c.Database
+	switch db.Driver {
+	case config.DriverPostgres:
+		if db.Host == "" || db.Port == "" || db.User == "" || db.Password == "" || db.Name == "" {
+			errs = append(errs, errors.New("the postgres driver requires DB_HOST, DB_PORT, DB_USER, DB_PASSWORD and DB_NAME"))
+		}
+	case config.DriverSQLite:
+		if db.Path == "" {
+			errs = append(errs, errors.New("the sqlite driver requires DB_PATH"))
+		}
+	case config.DriverMemory:
+	default:
+		errs = append(errs, fmt.Errorf("unknown database driver %q (expected postgres, sqlite or memory)", db.Driver))
+	}
+	return errors.Join(errs...)
+}
+
+// validOrigin reports whether origin is scheme://host[:port], where the
+// host may start with a *. wildcard label matching any subdomain.
+func validOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return false
+	}
+	host := strings.TrimPrefix(u.Hostname(), "*.")
+	return host != "" && !strings.Contains(host, "*")
+}
+
+// parseLevel parses a level name such as debug, info, warn or error.
+func parseLevel(dest *slog.Level, name, value string) error {
+	if err := dest.UnmarshalText([]byte(value)); err != nil {
+		return fmt.Errorf("invalid %s %q (expected debug, info, warn or error)", name, value)
+	}
+	return nil
+}
+
+func mergeString(dest *string, value string) {
+	if value != "" {
+		*dest = value
+	}
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(file, []byte(`{
+		"port": 4000,
+		"cors_origins": ["https://file.example"],
+		"read_timeout": "5s",
+		"tracing": {"exporter": "otlp", "sample_ratio": 0},
+		"database": {"driver": "sqlite", "path": "file.db"}
+	}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		env   map[string]string
+		args  []string
+		check func(t *testing.T, cfg Config)
+	}{
+		{
+			name: "file overrides defaults",
+			env:  map[string]string{"CONFIG_FILE": file},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Port != 4000 || cfg.ReadTimeout != 5*time.Second || cfg.Database.Path != "file.db" {
+					t.Errorf("got %+v", cfg)
+				}
+				if cfg.Tracing.Exporter != "otlp" || cfg.Tracing.SampleRatio != 0 {
+					t.Errorf("tracing %+v, want otlp with sampling off", cfg.Tracing)
+				}
+				if cfg.WriteTimeout != DefaultConfig().WriteTimeout {
+					t.Errorf("write timeout %s, want the default", cfg.WriteTimeout)
+				}
+			},
+		},
+		{
+			name: "env overrides file",
+			env:  map[string]string{"CONFIG_FILE": file, "PORT": "5000", "CORS_ORIGINS": "https://a.example, https://b.example", "LOG_LEVEL": "warn", "LOG_FORMAT": "text"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Port != 5000 {
+					t.Errorf("port %d, want 5000", cfg.Port)
+				}
+				if cfg.LogLevel != slog.LevelWarn || cfg.LogFormat != "text" {
+					t.Errorf("log level %s format %q, want WARN text", cfg.LogLevel, cfg.LogFormat)
+				}
+				want := []string{"https://a.example", "https://b.example"}
+				if !reflect.DeepEqual(cfg.CORS.Origins, want) {
+					t.Errorf("origins %v, want %v", cfg.CORS.Origins, want)
+				}
+			},
+		},
+		{
+			name: "flags override env",
+			env:  map[string]string{"PORT": "5000", "DB_DRIVER": "sqlite"},
+			args: []string{"-port", "6000", "-db-driver", "memory", "-idle-timeout", "2m", "-log-level", "debug"},
+			check: func(t *testing.T, cfg Config) {
+				if cfg.Port != 6000 || cfg.Database.Driver != "memory" || cfg.IdleTimeout != 2*time.Minute || cfg.LogLevel != slog.LevelDebug {
+					t.Errorf("got %+v", cfg)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := DefaultConfig()
+	valid.Database.Driver = "memory"
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr string
+	}{
+		{"valid", func(cfg *Config) {}, ""},
+		{"port out of range", func(cfg *Config) { cfg.Port = 70000 }, "port must be between"},
+		{"negative timeout", func(cfg *Config) { cfg.ReadTimeout = -time.Second }, "timeouts must not be negative"},
+		{"no origins", func(cfg *Config) { cfg.CORS.Origins = nil }, "at least one CORS origin"},
+		{"origin with path", func(cfg *Config) { cfg.CORS.Origins = []string{"https://a.example/app"} }, `CORS origin "https://a.example/app"`},
+		{"wildcard subdomain origin", func(cfg *Config) { cfg.CORS.Origins = []string{"https://*.vercel.app"} }, ""},
+		{"wildcard inside host", func(cfg *Config) { cfg.CORS.Origins = []string{"https://app-*.vercel.app"} }, `CORS origin "https://app-*.vercel.app"`},
+		{"any origin for writes", func(cfg *Config) { cfg.CORS.WriteOrigins = []string{"*"} }, "* is not allowed for writes"},
+		{"read method as write method", func(cfg *Config) { cfg.CORS.WriteMethods = []string{"GET"} }, `CORS write method "GET"`},
+		{"negative CORS max age", func(cfg *Config) { cfg.CORS.MaxAge = -time.Second }, "CORS max age must not be negative"},
+		{"negative trash retention", func(cfg *Config) { cfg.TrashRetention = -time.Hour }, "trash retention must not be negative"},
+		{"unknown log format", func(cfg *Config) { cfg.LogFormat = "xml" }, `log format must be json or text, got "xml"`},
+		{"unknown tracing exporter", func(cfg *Config) { cfg.Tracing.Exporter = "jaeger" }, `unknown tracing exporter "jaeger"`},
+		{"sample ratio above 1", func(cfg *Config) { cfg.Tracing.SampleRatio = 1.5 }, "sample ratio must be between 0 and 1"},
+		{"tracing endpoint without scheme", func(cfg *Config) { cfg.Tracing.Endpoint = "collector:4318" }, "must be an http or https URL"},
+		{"bad trusted proxy", func(cfg *Config) { cfg.TrustedProxies = []string{"nginx.local"} }, `trusted proxy "nginx.local" must be an IP address or CIDR range`},
+		{"unknown proxy header", func(cfg *Config) { cfg.ProxyHeader = "Forwarded" }, "proxy header must be X-Real-IP or X-Forwarded-For"},
+		{"redis store without URL", func(cfg *Config) { cfg.RateLimit.Store = "redis" }, "requires RATE_LIMIT_REDIS_URL"},
+		{"negative budget", func(cfg *Config) { cfg.RateLimit.Writes = -1 }, "budgets must not be negative"},
+		{"unknown driver", func(cfg *Config) { cfg.Database.Driver = "mysql" }, `unknown database driver "mysql"`},
+		{"postgres without settings", func(cfg *Config) { cfg.Database.Driver = "postgres" }, "requires DB_HOST"},
+		{"sqlite without path", func(cfg *Config) {
+			cfg.Database = valid.Database
+			cfg.Database.Driver = "sqlite"
+			cfg.Database.Path = ""
+		}, "requires DB_PATH"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsBadInput(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+	}{
+		{"non-numeric port", map[string]string{"PORT": "http"}, nil},
+		{"bad duration", map[string]string{"READ_TIMEOUT": "soon"}, nil},
+		{"non-numeric budget", map[string]string{"RATE_LIMIT_READS": "lots"}, nil},
+		{"unknown log level", map[string]string{"LOG_LEVEL": "chatty"}, nil},
+		{"missing file", map[string]string{"CONFIG_FILE": "/does/not/exist.json"}, nil},
+		{"unknown flag", nil, []string{"-nope"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("DB_DRIVER", "memory")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if _, err := Load(tt.args); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+// clearEnv unsets every variable Load reads for the rest of the test.
+func clearEnv(t *testing.T) {
+	for _, key := range []string{
+		"CONFIG_FILE", "PORT", "READ_TIMEOUT", "WRITE_TIMEOUT", "IDLE_TIMEOUT", "SHUTDOWN_TIMEOUT", "REFRESH_TOKEN_TTL", "TRASH_RETENTION",
+		"CORS_ORIGINS", "CORS_WRITE_ORIGINS", "CORS_WRITE_METHODS", "CORS_HEADERS", "CORS_ALLOW_CREDENTIALS", "CORS_MAX_AGE",
+		"LOG_LEVEL", "LOG_FORMAT", "TRACING_EXPORTER", "TRACING_ENDPOINT", "TRACING_SAMPLE_RATIO",
+		"TRUSTED_PROXIES", "PROXY_HEADER", "RATE_LIMIT_STORE", "RATE_LIMIT_REDIS_URL", "RATE_LIMIT_WINDOW", "RATE_LIMIT_READS", "RATE_LIMIT_WRITES",
+		"DB_DRIVER", "DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_SSLMODE", "DB_PATH",
+	} {
+		t.Setenv(key, "")
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"wannn-site-rebuild-api/repository"
+)
+
+func TestCORS(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CORS.Origins = []string{"https://wandhx.site", "https://*.vercel.app"}
+	cfg.CORS.WriteOrigins = []string{"https://admin.wandhx.site"}
+	cfg.CORS.AllowCredentials = true
+	app := New(cfg, Deps{Repos: repository.NewMemory()})
+
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		preflightOf string
+		wantOrigin  string
+		wantCreds   bool
+	}{
+		{"read from exact origin", "GET", "https://wandhx.site", "", "https://wandhx.site", false},
+		{"read from wildcard subdomain", "GET", "https://preview-42.vercel.app", "", "https://preview-42.vercel.app", false},
+		{"read from unknown origin", "GET", "https://evil.example", "", "", false},
+		{"read preflight", "OPTIONS", "https://preview-42.vercel.app", "GET", "https://preview-42.vercel.app", false},
+		{"write preflight from read origin", "OPTIONS", "https://wandhx.site", "POST", "", false},
+		{"write preflight from wildcard subdomain", "OPTIONS", "https://preview-42.vercel.app", "DELETE", "", false},
+		{"write preflight from write origin", "OPTIONS", "https://admin.wandhx.site", "PUT", "https://admin.wandhx.site", true},
+		{"write from write origin", "POST", "https://admin.wandhx.site", "", "https://admin.wandhx.site", true},
+		{"write from read origin", "POST", "https://wandhx.site", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/projects", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.preflightOf != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.preflightOf)
+			}
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.Header.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin %q, want %q", got, tt.wantOrigin)
+			}
+			if got := resp.Header.Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCreds {
+				t.Errorf("credentials allowed %v, want %v", got, tt.wantCreds)
+			}
+		})
+	}
+}
+
+func TestCORSDefaults(t *testing.T) {
+	app := New(DefaultConfig(), Deps{Repos: repository.NewMemory()})
+
+	// Any origin may read, but no origin may write until one is configured
+	read := httptest.NewRequest("GET", "/projects", nil)
+	read.Header.Set("Origin", "https://anywhere.example")
+	resp, err := app.Test(read, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("read Access-Control-Allow-Origin %q, want *", got)
+	}
+
+	preflight := httptest.NewRequest("OPTIONS", "/projects", nil)
+	preflight.Header.Set("Origin", "https://anywhere.example")
+	preflight.Header.Set("Access-Control-Request-Method", "POST")
+	resp, err = app.Test(preflight, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("write preflight Access-Control-Allow-Origin %q, want none", got)
+	}
+}
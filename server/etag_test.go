@@ -0,0 +1,106 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	const project = `{"title":"Portfolio","description":"My site","technologies":["Go"],"link":"","position":0}`
+
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			_, created := s.do(http.MethodPost, "/projects", project)
+			path := "/projects/" + strconv.Itoa(int(created["id"].(float64)))
+
+			get := func(ifNoneMatch string) *http.Response {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				if ifNoneMatch != "" {
+					req.Header.Set("If-None-Match", ifNoneMatch)
+				}
+				resp, err := s.app.Test(req, -1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return resp
+			}
+
+			resp := get("")
+			if tag := resp.Header.Get("ETag"); tag != `"1"` {
+				t.Fatalf("ETag %q, want \"1\"", tag)
+			}
+			if resp := get(`"1"`); resp.StatusCode != http.StatusNotModified {
+				t.Errorf("GET with a current If-None-Match: status %d, want 304", resp.StatusCode)
+			}
+
+			write := func(method, ifMatch, body string) (int, map[string]interface{}) {
+				var header map[string]string
+				if ifMatch != "" {
+					header = map[string]string{"If-Match": ifMatch}
+				}
+				return s.doWith(method, path, body, header)
+			}
+			if status, body := write(http.MethodPut, "", project); status != http.StatusPreconditionRequired || body["code"] != "precondition_required" {
+				t.Errorf("PUT without If-Match: status %d, body %v", status, body)
+			}
+			if status, body := write(http.MethodPut, `"1"`, project); status != http.StatusOK || body["version"] != float64(2) {
+				t.Fatalf("PUT with the current ETag: status %d, body %v", status, body)
+			}
+
+			// A second writer that read version 1 must not overwrite version 2
+			if status, body := write(http.MethodPut, `"1"`, project); status != http.StatusPreconditionFailed || body["code"] != "precondition_failed" {
+				t.Errorf("PUT with a stale ETag: status %d, body %v", status, body)
+			}
+			if status, _ := write(http.MethodPut, `W/"2"`, project); status != http.StatusPreconditionFailed {
+				t.Errorf("PUT with a weak ETag: status %d, want 412", status)
+			}
+			if status, _ := write(http.MethodDelete, `"1"`, ""); status != http.StatusPreconditionFailed {
+				t.Errorf("DELETE with a stale ETag: status %d, want 412", status)
+			}
+
+			if resp := get(`"1"`); resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != `"2"` {
+				t.Errorf("GET with a stale If-None-Match: status %d ETag %q, want 200 \"2\"", resp.StatusCode, resp.Header.Get("ETag"))
+			}
+
+			if status, body := write(http.MethodPut, `"7", "2"`, project); status != http.StatusOK || body["version"] != float64(3) {
+				t.Errorf("PUT with the current ETag in a list: status %d, body %v", status, body)
+			}
+			if status, _ := write(http.MethodDelete, "*", ""); status != http.StatusNoContent {
+				t.Errorf("DELETE with If-Match *: status %d, want 204", status)
+			}
+		})
+	}
+}
+
+func TestConditionalList(t *testing.T) {
+	s := newTestServer(t, backends[0].open)
+	s.do(http.MethodPost, "/projects", `{"title":"Portfolio","description":"My site","technologies":["Go"]}`)
+
+	list := func(ifNoneMatch string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/projects", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := s.app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	tag := list("").Header.Get("ETag")
+	if tag == "" {
+		t.Fatal("list has no ETag")
+	}
+	if resp := list(tag); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("list with a current If-None-Match: status %d, want 304", resp.StatusCode)
+	}
+
+	s.do(http.MethodPost, "/projects", `{"title":"Another","description":"More","technologies":["Go"]}`)
+	if resp := list(tag); resp.StatusCode != http.StatusOK {
+		t.Errorf("list after a change: status %d, want 200", resp.StatusCode)
+	}
+}
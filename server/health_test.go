@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"gorm.io/gorm"
+	"wannn-site-rebuild-api/config"
+	"wannn-site-rebuild-api/migrations"
+	"wannn-site-rebuild-api/repository"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	openSQLite := func(t *testing.T, migrate bool) *gorm.DB {
+		db, err := config.OpenSQLite(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		})
+		if migrate {
+			if _, err := migrations.Up(db); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return db
+	}
+
+	tests := []struct {
+		name       string
+		db         func(t *testing.T) *gorm.DB
+		wantStatus int
+		wantChecks map[string]string
+	}{
+		{"memory driver", func(t *testing.T) *gorm.DB { return nil }, http.StatusOK,
+			map[string]string{"database": "skipped: no SQL database"}},
+		{"migrated database", func(t *testing.T) *gorm.DB { return openSQLite(t, true) }, http.StatusOK,
+			map[string]string{"database": "ok", "migrations": "ok"}},
+		{"pending migrations", func(t *testing.T) *gorm.DB { return openSQLite(t, false) }, http.StatusServiceUnavailable,
+			map[string]string{"database": "ok", "migrations": strconv.Itoa(len(migrations.All())) + " pending, run migrate up"}},
+		{"closed connection", func(t *testing.T) *gorm.DB {
+			db := openSQLite(t, true)
+			sqlDB, _ := db.DB()
+			sqlDB.Close()
+			return db
+		}, http.StatusServiceUnavailable, map[string]string{"database": "unreachable", "migrations": "skipped: database unreachable"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Database.Driver = config.DriverMemory
+			s := &testServer{t: t, app: New(cfg, Deps{Repos: repository.NewMemory(), DB: tt.db(t)})}
+
+			if status, body := s.send("GET", "/healthz", "", ""); status != http.StatusOK || body["status"] != "ok" {
+				t.Errorf("healthz: got %d %v", status, body)
+			}
+
+			status, body := s.send("GET", "/readyz", "", "")
+			if status != tt.wantStatus {
+				t.Fatalf("readyz: got %d %v, want %d", status, body, tt.wantStatus)
+			}
+			checks, _ := body["checks"].(map[string]interface{})
+			for name, want := range tt.wantChecks {
+				if checks[name] != want {
+					t.Errorf("readyz check %s: got %v, want %q", name, checks[name], want)
+				}
+			}
+		})
+	}
+
+	s := &testServer{t: t, app: New(DefaultConfig(), Deps{Repos: repository.NewMemory()})}
+	status, body := s.send("GET", "/version", "", "")
+	if status != http.StatusOK || body["version"] == nil || body["commit"] == nil || body["build_time"] == nil {
+		t.Errorf("version: got %d %v", status, body)
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"wannn-site-rebuild-api/logging"
+)
+
+func TestRequestLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, slog.LevelInfo, logging.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	s := newTestServer(t, backends[0].open)
+	req := httptest.NewRequest("POST", "/projects", strings.NewReader(`{"title":"Logged","description":"d","technologies":["Go"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", s.apiKey)
+	req.Header.Set("X-Request-ID", "trace-42")
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get("X-Request-ID"); got != "trace-42" {
+		t.Errorf("response request ID %q, want trace-42", got)
+	}
+	s.send("GET", "/projects/999", "", "")
+
+	var lines []map[string]interface{}
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var line map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", raw, err)
+		}
+		if line["msg"] == "request" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) != 2 {
+		t.Fatalf("got %d request lines, want 2:\n%s", len(lines), buf.String())
+	}
+
+	created := lines[0]
+	want := map[string]interface{}{
+		"level": "INFO", "request_id": "trace-42", "method": "POST", "path": "/projects",
+		"route": "/projects/", "status": float64(201), "auth_method": "api_key",
+	}
+	for k, v := range want {
+		if created[k] != v {
+			t.Errorf("%s = %v, want %v", k, created[k], v)
+		}
+	}
+	if user, _ := created["user"].(string); !strings.HasPrefix(user, "api_key:") {
+		t.Errorf("user = %v, want the API key prefix", created["user"])
+	}
+	if _, ok := created["latency_ms"].(float64); !ok {
+		t.Errorf("latency_ms = %v, want a number", created["latency_ms"])
+	}
+
+	missing := lines[1]
+	if missing["status"] != float64(404) || missing["route"] != "/projects/:id" || missing["error"] == nil || missing["user"] != nil {
+		t.Errorf("got %v", missing)
+	}
+	if id, _ := missing["request_id"].(string); id == "" {
+		t.Error("a request ID is generated when none is sent")
+	}
+}
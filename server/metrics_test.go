@@ -0,0 +1,52 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			s.do("POST", "/projects", `{"title":"Metrics","description":"d","technologies":["Go"]}`)
+			s.do("GET", "/projects/1", "")
+			s.do("GET", "/projects/999", "")
+			s.do("GET", "/no-such-route", "")
+
+			resp, err := s.app.Test(httptest.NewRequest("GET", "/metrics", nil), -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			raw, _ := io.ReadAll(resp.Body)
+			body := string(raw)
+
+			if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+				t.Fatalf("got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+			}
+			for _, want := range []string{
+				`wandhx_http_requests_total{method="POST",route="/projects/",status="201"} 1`,
+				`wandhx_http_requests_total{method="GET",route="/projects/:id",status="200"} 1`,
+				`wandhx_http_requests_total{method="GET",route="/projects/:id",status="404"} 1`,
+				`wandhx_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+				`wandhx_http_request_duration_seconds_count{method="GET",route="/projects/:id"} 2`,
+				`wandhx_http_request_duration_seconds_bucket{method="GET",route="/projects/:id",le="+Inf"} 2`,
+				`wandhx_http_requests_in_flight 1`,
+				`wandhx_rows{entity="projects"} 1`,
+				`wandhx_rows{entity="experiences"} 0`,
+				"# TYPE wandhx_http_request_duration_seconds histogram",
+			} {
+				if !strings.Contains(body, want+"\n") {
+					t.Errorf("metrics missing %q", want)
+				}
+			}
+			if strings.Contains(body, "/no-such-route") {
+				t.Error("unmatched paths must not become route labels")
+			}
+		})
+	}
+}
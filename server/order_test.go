@@ -0,0 +1,179 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReorder(t *testing.T) {
+	for _, b := range backends {
+		for _, r := range resources {
+			t.Run(b.name+"/"+r.name, func(t *testing.T) {
+				s := newTestServer(t, b.open)
+				var ids []float64
+				versions := map[float64]float64{}
+				for i := 0; i < 3; i++ {
+					_, created := s.do(http.MethodPost, r.path, r.create)
+					if created["position"] != float64(i) {
+						t.Fatalf("item %d created at position %v, want it last", i, created["position"])
+					}
+					ids = append(ids, created["id"].(float64))
+					versions[created["id"].(float64)] = created["version"].(float64)
+				}
+
+				// listed summarises a list as "id@position vVersion" entries
+				// and remembers the versions for the next order
+				listed := func(body map[string]interface{}) string {
+					t.Helper()
+					var got []string
+					for _, item := range body["data"].([]interface{}) {
+						record := item.(map[string]interface{})
+						versions[record["id"].(float64)] = record["version"].(float64)
+						got = append(got, fmt.Sprintf("%v@%v v%v", record["id"], record["position"], record["version"]))
+					}
+					return strings.Join(got, ", ")
+				}
+				order := func(ids ...float64) string {
+					items := []map[string]float64{}
+					for _, id := range ids {
+						items = append(items, map[string]float64{"id": id, "version": versions[id]})
+					}
+					raw, _ := json.Marshal(map[string]interface{}{"items": items})
+					return string(raw)
+				}
+
+				// The third item keeps its position, so only the first two
+				// get a new version
+				stale := order(ids...)
+				status, body := s.do(http.MethodPut, r.path+"/order", order(ids[1], ids[0], ids[2]))
+				if status != http.StatusOK {
+					t.Fatalf("reorder: status %d, body %v", status, body)
+				}
+				want := fmt.Sprintf("%v@0 v2, %v@1 v2, %v@2 v1", ids[1], ids[0], ids[2])
+				if got := listed(body); got != want {
+					t.Errorf("reordered %s, want %s", got, want)
+				}
+				if _, body := s.do(http.MethodGet, r.path, ""); listed(body) != want {
+					t.Errorf("list after reorder %s, want %s", listed(body), want)
+				}
+
+				versions[999] = 1
+				tests := []struct {
+					name       string
+					body       string
+					wantStatus int
+				}{
+					{"stale versions", stale, http.StatusPreconditionFailed},
+					{"missing version", fmt.Sprintf(`{"items":[{"id":%v},{"id":%v},{"id":%v}]}`, ids[0], ids[1], ids[2]), http.StatusPreconditionRequired},
+					{"missing an item", order(ids[0], ids[1]), http.StatusConflict},
+					{"unknown item", order(ids[0], ids[1], ids[2], 999), http.StatusConflict},
+					{"duplicate item", order(ids[0], ids[0], ids[1], ids[2]), http.StatusUnprocessableEntity},
+					{"no items", `{"items":[]}`, http.StatusUnprocessableEntity},
+				}
+				for _, tt := range tests {
+					if status, body := s.do(http.MethodPut, r.path+"/order", tt.body); status != tt.wantStatus {
+						t.Errorf("%s: status %d, want %d, body %v", tt.name, status, tt.wantStatus, body)
+					}
+				}
+				if _, body := s.do(http.MethodGet, r.path, ""); listed(body) != want {
+					t.Errorf("list after rejected orders %s, want %s", listed(body), want)
+				}
+
+				if status, _ := s.send(http.MethodPut, r.path+"/order", order(ids...), ""); status != http.StatusUnauthorized {
+					t.Errorf("anonymous reorder: status %d, want 401", status)
+				}
+			})
+		}
+	}
+}
+
+func TestPositionOnWrite(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			_, first := s.do(http.MethodPost, "/projects", `{"title":"First","description":"d","technologies":["Go"],"position":5}`)
+			if first["position"] != float64(5) {
+				t.Fatalf("position %v, want 5", first["position"])
+			}
+			_, second := s.do(http.MethodPost, "/projects", `{"title":"Second","description":"d","technologies":["Go"]}`)
+			if second["position"] != float64(6) {
+				t.Errorf("position %v, want 6 after the last project", second["position"])
+			}
+			if status, body := s.do(http.MethodPost, "/projects", `{"title":"Bad","description":"d","technologies":["Go"],"position":-1}`); status != http.StatusUnprocessableEntity {
+				t.Errorf("negative position: status %d, body %v", status, body)
+			}
+
+			path := "/projects/" + strconv.Itoa(int(second["id"].(float64)))
+			// PUT replaces the whole record, so it must say where it goes
+			status, body := s.doWith(http.MethodPut, path, `{"title":"Second","description":"changed","technologies":["Go"]}`, ifMatch(second))
+			if status != http.StatusUnprocessableEntity || body["errors"].(map[string]interface{})["position"] != "is required" {
+				t.Fatalf("PUT without a position: status %d, body %v, want 422", status, body)
+			}
+			status, body = s.doWith(http.MethodPut, path, `{"title":"Second","description":"changed","technologies":["Go"],"position":3}`, ifMatch(second))
+			if status != http.StatusOK || body["position"] != float64(3) {
+				t.Fatalf("PUT with a position: status %d, body %v", status, body)
+			}
+
+			header := ifMatch(body)
+			header["Content-Type"] = mergePatch
+			status, body = s.doWith(http.MethodPatch, path, `{"position":0}`, header)
+			if status != http.StatusOK || body["position"] != float64(0) {
+				t.Fatalf("PATCH position: status %d, body %v", status, body)
+			}
+
+			_, body = s.do(http.MethodGet, "/projects", "")
+			if got := body["data"].([]interface{})[0].(map[string]interface{})["title"]; got != "Second" {
+				t.Errorf("first listed project %v, want Second", got)
+			}
+			_, body = s.do(http.MethodGet, "/projects?sort=title", "")
+			if got := body["data"].([]interface{})[0].(map[string]interface{})["title"]; got != "First" {
+				t.Errorf("first project sorted by title %v, want First", got)
+			}
+		})
+	}
+}
+
+func TestReorderSkills(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			_, created := s.do(http.MethodPost, "/skills", `{"title":"Languages","skills":["Go","TypeScript","Python"]}`)
+			path := "/skills/" + strconv.Itoa(int(created["id"].(float64))) + "/order"
+
+			status, body := s.doWith(http.MethodPut, path, `{"skills":["Python","Go","TypeScript"]}`, ifMatch(created))
+			if status != http.StatusOK || body["version"] != float64(2) {
+				t.Fatalf("reorder: status %d, body %v", status, body)
+			}
+			assertArray(t, "reorder", body["skills"], []string{"Python", "Go", "TypeScript"})
+
+			tests := []struct {
+				name       string
+				body       string
+				header     map[string]string
+				wantStatus int
+			}{
+				{"stale version", `{"skills":["Go","Python","TypeScript"]}`, ifMatch(created), http.StatusPreconditionFailed},
+				{"no If-Match", `{"skills":["Go","Python","TypeScript"]}`, nil, http.StatusPreconditionRequired},
+				{"missing a skill", `{"skills":["Go","Python"]}`, ifMatch(body), http.StatusConflict},
+				{"unknown skill", `{"skills":["Go","Python","Rust"]}`, ifMatch(body), http.StatusConflict},
+				{"duplicate skill", `{"skills":["Go","Go","Python"]}`, ifMatch(body), http.StatusUnprocessableEntity},
+				{"no skills", `{"skills":[]}`, ifMatch(body), http.StatusUnprocessableEntity},
+			}
+			for _, tt := range tests {
+				if status, got := s.doWith(http.MethodPut, path, tt.body, tt.header); status != tt.wantStatus {
+					t.Errorf("%s: status %d, want %d, body %v", tt.name, status, tt.wantStatus, got)
+				}
+			}
+
+			_, stored := s.do(http.MethodGet, "/skills/"+strconv.Itoa(int(created["id"].(float64))), "")
+			assertArray(t, "after rejected orders", stored["skills"], []string{"Python", "Go", "TypeScript"})
+			if status, _ := s.sendWith(http.MethodPut, path, `{"skills":["Go","Python","TypeScript"]}`, "", ifMatch(stored)); status != http.StatusUnauthorized {
+				t.Errorf("anonymous reorder: status %d, want 401", status)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+const (
+	mergePatch = "application/merge-patch+json"
+	jsonPatch  = "application/json-patch+json"
+)
+
+func TestPatchProject(t *testing.T) {
+	const project = `{"title":"Portfolio","description":"My site","technologies":["Go","Fiber"],"link":"https://example.com"}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string
+		want        map[string]interface{}
+	}{
+		{
+			name:        "merge patch keeps untouched fields",
+			contentType: mergePatch,
+			body:        `{"title":"Portfolio v2"}`,
+			wantStatus:  http.StatusOK,
+			want:        map[string]interface{}{"title": "Portfolio v2", "link": "https://example.com", "technologies": []string{"Go", "Fiber"}},
+		},
+		{
+			name:        "merge patch null clears a field",
+			contentType: mergePatch + "; charset=utf-8",
+			body:        `{"link":null}`,
+			wantStatus:  http.StatusOK,
+			want:        map[string]interface{}{"title": "Portfolio", "link": "", "technologies": []string{"Go", "Fiber"}},
+		},
+		{
+			name:        "merge patch replaces arrays",
+			contentType: mergePatch,
+			body:        `{"technologies":["Rust"]}`,
+			wantStatus:  http.StatusOK,
+			want:        map[string]interface{}{"technologies": []string{"Rust"}},
+		},
+		{
+			name:        "merge patch clearing a required field",
+			contentType: mergePatch,
+			body:        `{"title":null}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    "validation_failed",
+		},
+		{
+			name:        "merge patch setting the id",
+			contentType: mergePatch,
+			body:        `{"id":99}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    "bad_request",
+		},
+		{
+			name:        "json patch appends to an array",
+			contentType: jsonPatch,
+			body:        `[{"op":"add","path":"/technologies/-","value":"Postgres"}]`,
+			wantStatus:  http.StatusOK,
+			want:        map[string]interface{}{"title": "Portfolio", "technologies": []string{"Go", "Fiber", "Postgres"}},
+		},
+		{
+			name:        "json patch inserts, removes and moves",
+			contentType: jsonPatch,
+			body: `[
+				{"op":"add","path":"/technologies/0","value":"Docker"},
+				{"op":"remove","path":"/technologies/1"},
+				{"op":"move","from":"/technologies/0","path":"/technologies/-"},
+				{"op":"copy","from":"/title","path":"/description"}
+			]`,
+			wantStatus: http.StatusOK,
+			want:       map[string]interface{}{"description": "Portfolio", "technologies": []string{"Fiber", "Docker"}},
+		},
+		{
+			name:        "json patch with a passing test",
+			contentType: jsonPatch,
+			body:        `[{"op":"test","path":"/technologies/1","value":"Fiber"},{"op":"replace","path":"/technologies/1","value":"Echo"}]`,
+			wantStatus:  http.StatusOK,
+			want:        map[string]interface{}{"technologies": []string{"Go", "Echo"}},
+		},
+		{
+			name:        "json patch with a failing test changes nothing",
+			contentType: jsonPatch,
+			body:        `[{"op":"replace","path":"/title","value":"Changed"},{"op":"test","path":"/link","value":"https://other.example"}]`,
+			wantStatus:  http.StatusConflict,
+			wantCode:    "conflict",
+		},
+		{
+			name:        "json patch on a missing index",
+			contentType: jsonPatch,
+			body:        `[{"op":"remove","path":"/technologies/5"}]`,
+			wantStatus:  http.StatusConflict,
+			wantCode:    "conflict",
+		},
+		{
+			name:        "json patch with an unknown op",
+			contentType: jsonPatch,
+			body:        `[{"op":"merge","path":"/title","value":"x"}]`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    "bad_request",
+		},
+		{
+			name:        "json patch that is not an array",
+			contentType: jsonPatch,
+			body:        `{"title":"x"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    "bad_request",
+		},
+		{
+			name:        "plain JSON body",
+			contentType: "application/json",
+			body:        `{"title":"x"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantCode:    "unsupported_media_type",
+		},
+	}
+
+	for _, b := range backends {
+		for _, tt := range tests {
+			t.Run(b.name+"/"+tt.name, func(t *testing.T) {
+				s := newTestServer(t, b.open)
+				_, created := s.do(http.MethodPost, "/projects", project)
+				path := "/projects/" + strconv.Itoa(int(created["id"].(float64)))
+
+				header := ifMatch(created)
+				header["Content-Type"] = tt.contentType
+				status, body := s.doWith(http.MethodPatch, path, tt.body, header)
+				if status != tt.wantStatus {
+					t.Fatalf("status %d, want %d, body %v", status, tt.wantStatus, body)
+				}
+				if tt.wantCode != "" {
+					if body["code"] != tt.wantCode {
+						t.Errorf("code %v, want %s", body["code"], tt.wantCode)
+					}
+					// A rejected patch leaves the project as it was
+					_, got := s.do(http.MethodGet, path, "")
+					if got["title"] != "Portfolio" {
+						t.Errorf("title %v after a rejected patch, want Portfolio", got["title"])
+					}
+					return
+				}
+
+				_, got := s.do(http.MethodGet, path, "")
+				for field, want := range tt.want {
+					if items, ok := want.([]string); ok {
+						assertArray(t, field, got[field], items)
+					} else if got[field] != want {
+						t.Errorf("%s %v, want %v", field, got[field], want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestPatchOtherResources(t *testing.T) {
+	for _, r := range resources {
+		t.Run(r.name, func(t *testing.T) {
+			s := newTestServer(t, backends[0].open)
+			_, created := s.do(http.MethodPost, r.path, r.create)
+			path := r.path + "/" + strconv.Itoa(int(created["id"].(float64)))
+
+			header := ifMatch(created)
+			header["Content-Type"] = jsonPatch
+			status, body := s.doWith(http.MethodPatch, path, `[{"op":"add","path":"/`+r.arrayField+`/-","value":"Added"}]`, header)
+			if status != http.StatusOK {
+				t.Fatalf("status %d, body %v", status, body)
+			}
+			assertArray(t, "patched", body[r.arrayField], append(r.created[:len(r.created):len(r.created)], "Added"))
+			if body["title"] != created["title"] {
+				t.Errorf("title %v, want %v unchanged", body["title"], created["title"])
+			}
+
+			if status, _ := s.sendWith(http.MethodPatch, path, `{"title":"x"}`, "", header); status != http.StatusUnauthorized {
+				t.Errorf("anonymous patch: status %d, want 401", status)
+			}
+			if status, _ := s.doWith(http.MethodPatch, r.path+"/999", `{"title":"x"}`, header); status != http.StatusNotFound {
+				t.Errorf("patch of a missing record: status %d, want 404", status)
+			}
+		})
+	}
+}
+
+func TestPatchUnchanged(t *testing.T) {
+	for _, b := range backends {
+		for _, r := range resources {
+			t.Run(b.name+"/"+r.name, func(t *testing.T) {
+				s := newTestServer(t, b.open)
+				_, created := s.do(http.MethodPost, r.path, r.create)
+				path := r.path + "/" + strconv.Itoa(int(created["id"].(float64)))
+				title, _ := json.Marshal(created["title"])
+
+				for _, p := range []struct{ contentType, body string }{
+					{mergePatch, `{"title":` + string(title) + `}`},
+					{mergePatch, `{"position":null}`},
+					{mergePatch, `{}`},
+					{jsonPatch, `[{"op":"test","path":"/title","value":` + string(title) + `}]`},
+				} {
+					header := ifMatch(created)
+					header["Content-Type"] = p.contentType
+					status, body := s.doWith(http.MethodPatch, path, p.body, header)
+					if status != http.StatusOK {
+						t.Fatalf("%s: status %d, body %v", p.body, status, body)
+					}
+					if body["version"] != created["version"] || body["updated_at"] != created["updated_at"] {
+						t.Errorf("%s: version %v updated %v, want %v and %v kept", p.body,
+							body["version"], body["updated_at"], created["version"], created["updated_at"])
+					}
+				}
+
+				_, got := s.do(http.MethodGet, path, "")
+				if got["version"] != created["version"] {
+					t.Errorf("stored version %v, want %v", got["version"], created["version"])
+				}
+			})
+		}
+	}
+}
+
+func TestPutReplacesProject(t *testing.T) {
+	s := newTestServer(t, backends[0].open)
+	_, created := s.do(http.MethodPost, "/projects", `{"title":"Portfolio","description":"My site","technologies":["Go"],"link":"https://example.com"}`)
+	path := "/projects/" + strconv.Itoa(int(created["id"].(float64)))
+
+	// PUT is a full replacement: an omitted optional field is cleared
+	status, body := s.doWith(http.MethodPut, path, `{"title":"Portfolio","description":"My site","technologies":["Go"],"position":0}`, ifMatch(created))
+	if status != http.StatusOK {
+		t.Fatalf("status %d, body %v", status, body)
+	}
+	if body["link"] != "" {
+		t.Errorf("link %v, want it cleared", body["link"])
+	}
+}
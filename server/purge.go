@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"wannn-site-rebuild-api/repository"
+)
+
+// purgeInterval is how often PurgeTrash looks for expired trash.
+const purgeInterval = time.Hour
+
+// PurgeTrash permanently removes content that has been in the trash for
+// longer than retention, once right away and then every hour until ctx is
+// done. A zero retention keeps the trash forever.
+func PurgeTrash(ctx context.Context, repos repository.Repositories, retention time.Duration) {
+	if retention == 0 {
+		return
+	}
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		purgeExpired(ctx, repos, time.Now().Add(-retention))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpired removes content deleted before cutoff and logs how much.
+func purgeExpired(ctx context.Context, repos repository.Repositories, cutoff time.Time) {
+	entities := []struct {
+		name  string
+		purge func(context.Context, time.Time) (int64, error)
+	}{
+		{"experiences", repos.Experiences.PurgeTrash},
+		{"projects", repos.Projects.PurgeTrash},
+		{"skill_categories", repos.SkillCategories.PurgeTrash},
+	}
+	for _, e := range entities {
+		n, err := e.purge(ctx, cutoff)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("Failed to purge expired trash", "entity", e.name, "error", err)
+			}
+			continue
+		}
+		if n > 0 {
+			slog.Info("Purged expired trash", "entity", e.name, "count", n, "deleted_before", cutoff)
+		}
+	}
+}
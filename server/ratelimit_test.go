@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"wannn-site-rebuild-api/ratelimit"
+	"wannn-site-rebuild-api/repository"
+)
+
+// stores are the rate limit stores every rate limit test runs against.
+// advance moves the store's clock forward.
+var stores = []struct {
+	name string
+	open func(t *testing.T) (store ratelimit.Store, advance func(time.Duration))
+}{
+	{"memory", func(t *testing.T) (ratelimit.Store, func(time.Duration)) {
+		return ratelimit.NewMemoryStore(), time.Sleep
+	}},
+	{"redis", func(t *testing.T) (ratelimit.Store, func(time.Duration)) {
+		mr := miniredis.RunT(t)
+		store, err := ratelimit.NewRedisStore("redis://" + mr.Addr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store, mr.FastForward
+	}},
+}
+
+func TestRateLimit(t *testing.T) {
+	for _, st := range stores {
+		t.Run(st.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.RateLimit.Reads, cfg.RateLimit.Writes = 3, 2
+			// app.Test connections come from 0.0.0.0
+			cfg.TrustedProxies = []string{"0.0.0.0"}
+			s := newTestServer(t, backends[0].open)
+			store, _ := st.open(t)
+			s.app = New(cfg, Deps{Repos: s.repos, RateLimitStore: store})
+
+			get := func(ip string) *http.Response {
+				req := httptest.NewRequest("GET", "/projects", nil)
+				if ip != "" {
+					req.Header.Set("X-Real-IP", ip)
+				}
+				resp, err := s.app.Test(req, -1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return resp
+			}
+
+			// Reads: 3 per window per client IP, with headers counting down
+			for i := 2; i >= 0; i-- {
+				resp := get("203.0.113.1")
+				if resp.StatusCode != http.StatusOK || resp.Header.Get("RateLimit-Remaining") != strconv.Itoa(i) {
+					t.Fatalf("read %d: got %d remaining %q", 3-i, resp.StatusCode, resp.Header.Get("RateLimit-Remaining"))
+				}
+				if resp.Header.Get("RateLimit-Limit") != "3" || resp.Header.Get("RateLimit-Policy") != "3;w=60" {
+					t.Errorf("limit %q policy %q", resp.Header.Get("RateLimit-Limit"), resp.Header.Get("RateLimit-Policy"))
+				}
+			}
+			resp := get("203.0.113.1")
+			if resp.StatusCode != http.StatusTooManyRequests {
+				t.Fatalf("read over budget: got %d", resp.StatusCode)
+			}
+			if retry, _ := strconv.Atoi(resp.Header.Get("Retry-After")); retry < 1 || retry > 60 {
+				t.Errorf("Retry-After %q, want 1-60 seconds", resp.Header.Get("Retry-After"))
+			}
+
+			// Another client behind the trusted proxy has its own budget
+			if resp := get("203.0.113.2"); resp.StatusCode != http.StatusOK {
+				t.Errorf("second client: got %d", resp.StatusCode)
+			}
+
+			// Probes are never limited
+			for i := 0; i < 5; i++ {
+				if status, _ := s.send("GET", "/healthz", "", ""); status != http.StatusOK {
+					t.Fatalf("healthz: got %d", status)
+				}
+			}
+
+			// Writes have their own budget, counted per API key
+			for i := 0; i < 2; i++ {
+				if status, body := s.do("POST", "/projects", `{"title":"P`+strconv.Itoa(i)+`","description":"d","technologies":["Go"]}`); status != http.StatusCreated {
+					t.Fatalf("write %d: got %d %v", i, status, body)
+				}
+			}
+			status, body := s.do("POST", "/projects", `{"title":"P2","description":"d","technologies":["Go"]}`)
+			if status != http.StatusTooManyRequests || body["code"] != "rate_limited" {
+				t.Fatalf("write over budget: got %d %v", status, body)
+			}
+			// ...so the key's reads and anonymous writes from the same IP are
+			// unaffected
+			if status, _ := s.do("GET", "/projects", ""); status != http.StatusOK {
+				t.Errorf("read with a spent write budget: got %d", status)
+			}
+			if status, _ := s.send("POST", "/auth/login", `{"email":"a@b.c","password":"x"}`, ""); status == http.StatusTooManyRequests {
+				t.Error("anonymous write shares the API key's budget")
+			}
+		})
+	}
+}
+
+func TestRateLimitClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted []string
+		header  string
+		shared  bool // whether two different header values share one budget
+	}{
+		{"header from a trusted proxy", []string{"0.0.0.0"}, "X-Real-IP", false},
+		{"header from an untrusted peer is ignored", []string{"10.0.0.1"}, "X-Real-IP", true},
+		{"forwarded-for from a trusted proxy", []string{"0.0.0.0/8"}, "X-Forwarded-For", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.RateLimit.Reads = 1
+			cfg.TrustedProxies = tt.trusted
+			cfg.ProxyHeader = tt.header
+			app := New(cfg, Deps{Repos: repository.NewMemory()})
+
+			var statuses []int
+			for _, ip := range []string{"198.51.100.7", "198.51.100.8"} {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.Header.Set(tt.header, ip)
+				resp, err := app.Test(req, -1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				statuses = append(statuses, resp.StatusCode)
+			}
+			shared := statuses[1] == fiber.StatusTooManyRequests
+			if shared != tt.shared {
+				t.Errorf("statuses %v, want shared budget: %v", statuses, tt.shared)
+			}
+		})
+	}
+}
+
+func TestRateLimitWindowResets(t *testing.T) {
+	for _, st := range stores {
+		t.Run(st.name, func(t *testing.T) {
+			store, advance := st.open(t)
+			limiter := ratelimit.New(store, ratelimit.Config{Window: 50 * time.Millisecond, Reads: 1})
+			ctx := context.Background()
+
+			if d, _, err := limiter.Allow(ctx, "ip:x", false); err != nil || !d.Allowed {
+				t.Fatalf("first hit: %+v %v", d, err)
+			}
+			if d, _, _ := limiter.Allow(ctx, "ip:x", false); d.Allowed {
+				t.Fatal("second hit within the window was allowed")
+			}
+			advance(60 * time.Millisecond)
+			if d, _, _ := limiter.Allow(ctx, "ip:x", false); !d.Allowed || d.Remaining != 0 {
+				t.Errorf("after the window: %+v", d)
+			}
+		})
+	}
+}
+
+func TestRateLimitStalledRedis(t *testing.T) {
+	// A server that accepts connections but never answers
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	store, err := ratelimit.NewRedisStore("redis://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	cfg := DefaultConfig()
+	cfg.RateLimit.Reads = 1
+	s := newTestServer(t, backends[0].open)
+	s.app = New(cfg, Deps{Repos: s.repos, RateLimitStore: store})
+
+	// Requests are let through, after a short store timeout rather than
+	// the client's default seconds of timeouts and retries
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		resp, err := s.app.Test(httptest.NewRequest("GET", "/projects", nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("request %d: status %d, want 200", i, resp.StatusCode)
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("request %d took %v", i, d)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"wannn-site-rebuild-api/auth"
+)
+
+func TestRolePermissions(t *testing.T) {
+	type request struct {
+		method, path, body string
+	}
+	var writes, deletes []request
+	for _, r := range resources {
+		writes = append(writes,
+			request{http.MethodPost, r.path, r.create},
+			request{http.MethodPut, r.path + "/1", r.update},
+			request{http.MethodPatch, r.path + "/1", `{}`},
+			request{http.MethodPut, r.path + "/order", `{"items":[{"id":1,"version":1}]}`},
+		)
+		deletes = append(deletes,
+			request{http.MethodDelete, r.path + "/1", ""},
+			request{http.MethodDelete, r.path + "/1?hard=true", ""},
+			request{http.MethodGet, r.path + "/trash", ""},
+			request{http.MethodPost, r.path + "/1/restore", ""},
+		)
+	}
+	writes = append(writes, request{http.MethodPut, "/skills/1/order", `{"skills":["Go"]}`})
+	users := []request{
+		{http.MethodGet, "/users", ""},
+		{http.MethodPost, "/users", `{"email":"new@example.com","password":"` + testPassword + `","role":"viewer"}`},
+		{http.MethodPut, "/users/1", `{"role":"owner"}`},
+		{http.MethodDelete, "/users/1", ""},
+	}
+
+	tests := []struct {
+		name     string
+		role     auth.Role
+		requests []request
+	}{
+		{"viewer writes", auth.RoleViewer, writes},
+		{"viewer deletes", auth.RoleViewer, deletes},
+		{"viewer manages users", auth.RoleViewer, users},
+		{"editor deletes", auth.RoleEditor, deletes},
+		{"editor manages users", auth.RoleEditor, users},
+	}
+
+	for _, b := range backends {
+		s := newTestServer(t, b.open)
+		for _, r := range resources {
+			if status, body := s.do(http.MethodPost, r.path, r.create); status != http.StatusCreated {
+				t.Fatalf("%s: create: status %d, body %v", r.name, status, body)
+			}
+		}
+		bearer := func(role auth.Role) map[string]string {
+			token, err := s.tokens.Sign("user:99", role)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return map[string]string{"Authorization": "Bearer " + token, "If-Match": "*"}
+		}
+
+		for _, tt := range tests {
+			t.Run(b.name+"/"+tt.name, func(t *testing.T) {
+				s.t = t
+				for _, req := range tt.requests {
+					status, body := s.sendWith(req.method, req.path, req.body, "", bearer(tt.role))
+					if status != http.StatusForbidden || body["code"] != "forbidden" {
+						t.Errorf("%s %s as %s: status %d, want 403 (body %v)", req.method, req.path, tt.role, status, body)
+					}
+				}
+			})
+		}
+
+		// The roles are not simply locked out: editors write, owners do
+		// everything
+		t.Run(b.name+"/allowed", func(t *testing.T) {
+			s.t = t
+			allowed := []struct {
+				role auth.Role
+				req  request
+				want int
+			}{
+				{auth.RoleEditor, request{http.MethodPost, "/projects", resources[1].create}, http.StatusCreated},
+				{auth.RoleEditor, request{http.MethodPatch, "/projects/1", `{"title":"Edited"}`}, http.StatusOK},
+				{auth.RoleOwner, request{http.MethodGet, "/projects/trash", ""}, http.StatusOK},
+				{auth.RoleOwner, users[1], http.StatusCreated},
+				{auth.RoleOwner, request{http.MethodDelete, "/projects/1", ""}, http.StatusNoContent},
+			}
+			for _, a := range allowed {
+				header := bearer(a.role)
+				if a.req.method == http.MethodPatch {
+					header["Content-Type"] = mergePatch
+				}
+				if status, body := s.sendWith(a.req.method, a.req.path, a.req.body, "", header); status != a.want {
+					t.Errorf("%s %s as %s: status %d, want %d (body %v)", a.req.method, a.req.path, a.role, status, a.want, body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSearchEscapesSnippets(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			s.do(http.MethodPost, "/projects", `{"title":"Roadinspex","description":"<script>alert('x')</script> road damage & <b>detection</b>","technologies":["Go"]}`)
+
+			status, body := s.do(http.MethodGet, "/search?q=damage", "")
+			if status != http.StatusOK {
+				t.Fatalf("status %d, body %v", status, body)
+			}
+			results := body["data"].([]interface{})
+			if len(results) != 1 {
+				t.Fatalf("results %v, want one", results)
+			}
+			snippet := results[0].(map[string]interface{})["snippet"].(string)
+
+			want := "&lt;script&gt;alert(&#39;x&#39;)&lt;/script&gt; road <mark>damage</mark> &amp; &lt;b&gt;detection&lt;/b&gt;"
+			if !strings.HasPrefix(snippet, want) {
+				t.Errorf("snippet %q, want it to start with %q", snippet, want)
+			}
+			if stripped := strings.NewReplacer("<mark>", "", "</mark>", "").Replace(snippet); strings.ContainsAny(stripped, "<>") {
+				t.Errorf("snippet %q contains markup besides <mark>", snippet)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Package server builds the HTTP API from a typed Config and its
+// dependencies, so it can be started, embedded or tested with any storage
+// backend.
+package server
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/etag"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"gorm.io/gorm"
+	"wannn-site-rebuild-api/apperr"
+	"wannn-site-rebuild-api/auth"
+	"wannn-site-rebuild-api/handlers"
+	"wannn-site-rebuild-api/metrics"
+	"wannn-site-rebuild-api/middleware"
+	"wannn-site-rebuild-api/ratelimit"
+	"wannn-site-rebuild-api/repository"
+	"wannn-site-rebuild-api/tracing"
+)
+
+// Deps are the services the API is built on.
+type Deps struct {
+	Repos repository.Repositories
+	// DB is the SQL database behind Repos, checked by /readyz. It is nil
+	// for the memory driver.
+	DB *gorm.DB
+	// Tokens signs and verifies JWTs. It may be nil when JWT authentication
+	// is disabled.
+	Tokens *auth.Tokens
+	// RateLimitStore holds the rate limit counters. When nil, each app
+	// counts in its own memory.
+	RateLimitStore ratelimit.Store
+}
+
+// Addr is the address the server listens on.
+func (c Config) Addr() string {
+	return ":" + strconv.Itoa(c.Port)
+}
+
+// New builds the Fiber app with every route wired to deps.
+func New(cfg Config, deps Deps) *fiber.App {
+	repos := deps.Repos
+
+	// Write routes accept an API key or a JWT; reads stay public. Each write
+	// route also checks that the caller's role grants the permission.
+	requireAuth := middleware.RequireAuth(repos.APIKeys, deps.Tokens)
+	canWrite := middleware.RequirePermission(auth.PermContentWrite)
+	canDelete := middleware.RequirePermission(auth.PermContentDelete)
+	canManageUsers := middleware.RequirePermission(auth.PermUsersManage)
+	authHandler := handlers.NewAuthHandler(repos.Users, repos.RefreshTokens, deps.Tokens, cfg.RefreshTokenTTL)
+
+	rateLimitStore := deps.RateLimitStore
+	if rateLimitStore == nil {
+		rateLimitStore = ratelimit.NewMemoryStore()
+	}
+	limiter := ratelimit.New(rateLimitStore, cfg.RateLimit)
+
+	// Create Fiber app; handlers return apperr errors rendered as problem+json
+	app := fiber.New(fiber.Config{
+		ErrorHandler: apperr.Handler,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+		// Run logs a structured line instead of the banner
+		DisableStartupMessage: true,
+		// c.IP() reads ProxyHeader only on requests from a trusted proxy
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.TrustedProxies,
+		ProxyHeader:             cfg.ProxyHeader,
+		EnableIPValidation:      true,
+	})
+
+	// Middleware
+	tracker := newInflight()
+	app.Hooks().OnShutdown(tracker.logCutOff)
+	app.Use(requestid.New())
+	app.Use(tracker.track)
+	app.Use(tracing.Middleware)
+	app.Use(middleware.RequestLogger)
+	httpMetrics := metrics.New()
+	app.Use(httpMetrics.Middleware)
+	app.Use(middleware.CORS(cfg.CORS))
+
+	// Routes
+	api := app.Group("/")
+
+	// Liveness, readiness, build info and Prometheus metrics for nginx and
+	// monitoring
+	healthHandler := handlers.NewHealthHandler(deps.DB)
+	api.Get("/healthz", healthHandler.Health)
+	api.Get("/readyz", healthHandler.Ready)
+	api.Get("/version", healthHandler.Version)
+	api.Get("/metrics", handlers.NewMetricsHandler(httpMetrics, repos, deps.DB).Metrics)
+
+	// Everything below is rate limited per API key, user or client IP.
+	// Probes and scrapes are registered above, so monitors are never
+	// throttled.
+	app.Use(middleware.Identify(repos.APIKeys, deps.Tokens), middleware.RateLimit(limiter))
+
+	// Lists and search answer If-None-Match with 304 using a weak ETag of
+	// the body. Single records carry their version as a strong ETag
+	conditional := etag.New(etag.Config{Weak: true})
+
+	// Home
+	api.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("Welcome to wandhx.site Backend API!")
+	})
+
+	// Search across projects, experiences and skills
+	api.Get("/search", conditional, handlers.NewSearchHandler(repos.Search).Search)
+
+	// Auth routes
+	authRoutes := api.Group("auth")
+	authRoutes.Post("/login", authHandler.Login)
+	authRoutes.Post("/refresh", authHandler.Refresh)
+	authRoutes.Post("/logout", authHandler.Logout)
+
+	// User management routes (owner only)
+	userHandler := handlers.NewUserHandler(repos.Users)
+	users := api.Group("users", requireAuth, canManageUsers)
+	users.Get("/", userHandler.List)
+	users.Post("/", userHandler.Create)
+	users.Put("/:id", userHandler.Update)
+	users.Delete("/:id", userHandler.Delete)
+
+	// Experience routes
+	experienceHandler := handlers.NewExperienceHandler(repos.Experiences)
+	experiences := api.Group("experiences")
+	experiences.Get("/", conditional, experienceHandler.List)
+	experiences.Get("/trash", requireAuth, canDelete, experienceHandler.Trash)
+	experiences.Get("/:id", experienceHandler.Get)
+	experiences.Post("/", requireAuth, canWrite, experienceHandler.Create)
+	experiences.Put("/order", requireAuth, canWrite, experienceHandler.Reorder)
+	experiences.Put("/:id", requireAuth, canWrite, experienceHandler.Update)
+	experiences.Patch("/:id", requireAuth, canWrite, experienceHandler.Patch)
+	experiences.Delete("/:id", requireAuth, canDelete, experienceHandler.Delete)
+	experiences.Post("/:id/restore", requireAuth, canDelete, experienceHandler.Restore)
+
+	// Project routes
+	projectHandler := handlers.NewProjectHandler(repos.Projects)
+	projects := api.Group("projects")
+	projects.Get("/", conditional, projectHandler.List)
+	projects.Get("/trash", requireAuth, canDelete, projectHandler.Trash)
+	projects.Get("/:id", projectHandler.Get)
+	projects.Post("/", requireAuth, canWrite, projectHandler.Create)
+	projects.Put("/order", requireAuth, canWrite, projectHandler.Reorder)
+	projects.Put("/:id", requireAuth, canWrite, projectHandler.Update)
+	projects.Patch("/:id", requireAuth, canWrite, projectHandler.Patch)
+	projects.Delete("/:id", requireAuth, canDelete, projectHandler.Delete)
+	projects.Post("/:id/restore", requireAuth, canDelete, projectHandler.Restore)
+
+	// Skill Category routes
+	skillHandler := handlers.NewSkillCategoryHandler(repos.SkillCategories)
+	skills := api.Group("skills")
+	skills.Get("/", conditional, skillHandler.List)
+	skills.Get("/trash", requireAuth, canDelete, skillHandler.Trash)
+	skills.Get("/:id", skillHandler.Get)
+	skills.Post("/", requireAuth, canWrite, skillHandler.Create)
+	skills.Put("/order", requireAuth, canWrite, skillHandler.Reorder)
+	skills.Put("/:id", requireAuth, canWrite, skillHandler.Update)
+	skills.Put("/:id/order", requireAuth, canWrite, skillHandler.ReorderSkills)
+	skills.Patch("/:id", requireAuth, canWrite, skillHandler.Patch)
+	skills.Delete("/:id", requireAuth, canDelete, skillHandler.Delete)
+	skills.Post("/:id/restore", requireAuth, canDelete, skillHandler.Restore)
+
+	return app
+}
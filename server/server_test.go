@@ -0,0 +1,484 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"wannn-site-rebuild-api/auth"
+	"wannn-site-rebuild-api/config"
+	"wannn-site-rebuild-api/migrations"
+	"wannn-site-rebuild-api/models"
+	"wannn-site-rebuild-api/repository"
+)
+
+// backends are the storage backends every test runs against.
+var backends = []struct {
+	name string
+	open func(t *testing.T) repository.Repositories
+}{
+	{"memory", func(t *testing.T) repository.Repositories {
+		return repository.NewMemory()
+	}},
+	{"sqlite", func(t *testing.T) repository.Repositories {
+		db, err := config.OpenSQLite(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		})
+		if _, err := migrations.Up(db); err != nil {
+			t.Fatal(err)
+		}
+		return repository.NewGORM(db)
+	}},
+}
+
+// testServer is an app on a throwaway backend plus an owner API key and
+// the JWT signer it trusts.
+type testServer struct {
+	t      *testing.T
+	app    *fiber.App
+	repos  repository.Repositories
+	apiKey string
+	tokens *auth.Tokens
+}
+
+func newTestServer(t *testing.T, open func(t *testing.T) repository.Repositories) *testServer {
+	t.Helper()
+	repos := open(t)
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := models.APIKey{Name: "test", Prefix: prefix, KeyHash: hash, Role: string(auth.RoleOwner)}
+	if err := repos.APIKeys.Create(context.Background(), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := auth.NewTokens(auth.TokenConfig{Algorithm: auth.HS256, Secret: []byte(testJWTSecret), Issuer: "wandhx-be"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Database.Driver = config.DriverMemory
+	return &testServer{t: t, app: New(cfg, Deps{Repos: repos, Tokens: tokens}), repos: repos, apiKey: key, tokens: tokens}
+}
+
+// testJWTSecret signs the access tokens of test servers.
+const testJWTSecret = "test-secret-of-at-least-32-bytes!"
+
+// do sends a request authenticated with the server's API key and returns
+// the status and decoded JSON body (nil when the body is empty).
+func (s *testServer) do(method, path, body string) (int, map[string]interface{}) {
+	s.t.Helper()
+	return s.send(method, path, body, s.apiKey)
+}
+
+func (s *testServer) send(method, path, body, apiKey string) (int, map[string]interface{}) {
+	s.t.Helper()
+	return s.sendWith(method, path, body, apiKey, nil)
+}
+
+// doWith is do with extra request headers. Content-Type defaults to JSON.
+func (s *testServer) doWith(method, path, body string, header map[string]string) (int, map[string]interface{}) {
+	s.t.Helper()
+	return s.sendWith(method, path, body, s.apiKey, header)
+}
+
+func (s *testServer) sendWith(method, path, body, apiKey string, header map[string]string) (int, map[string]interface{}) {
+	s.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if len(raw) == 0 {
+		return resp.StatusCode, nil
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		s.t.Fatalf("%s %s: decoding %q: %v", method, path, raw, err)
+	}
+	return resp.StatusCode, decoded
+}
+
+// resources describes each content type: a valid create and update body
+// and the array field whose contents must round-trip exactly.
+var resources = []struct {
+	name       string
+	path       string
+	create     string
+	update     string
+	arrayField string
+	created    []string
+	updated    []string
+}{
+	{
+		name:       "experiences",
+		path:       "/experiences",
+		create:     `{"title":"Backend Engineer","company":"Acme","period":"2023 - Present","description":["Built APIs","Led, \"mentored\" and {reviewed}"]}`,
+		update:     `{"title":"Senior Backend Engineer","company":"Acme","period":"2023 - 2025","description":["C:\\path\\to","Ünïcode ✓"],"position":0}`,
+		arrayField: "description",
+		created:    []string{"Built APIs", `Led, "mentored" and {reviewed}`},
+		updated:    []string{`C:\path\to`, "Ünïcode ✓"},
+	},
+	{
+		name:       "projects",
+		path:       "/projects",
+		create:     `{"title":"Portfolio","description":"My site","technologies":["Go","C, C++","NULL"],"link":"https://example.com"}`,
+		update:     `{"title":"Portfolio v2","description":"My new site","technologies":["{braces}","back\\slash"],"link":"","position":0}`,
+		arrayField: "technologies",
+		created:    []string{"Go", "C, C++", "NULL"},
+		updated:    []string{"{braces}", `back\slash`},
+	},
+	{
+		name:       "skill categories",
+		path:       "/skills",
+		create:     `{"title":"Languages","skills":["Go","TypeScript"]}`,
+		update:     `{"title":"Programming Languages","skills":["Go","\"quoted\"","a,b"],"position":0}`,
+		arrayField: "skills",
+		created:    []string{"Go", "TypeScript"},
+		updated:    []string{"Go", `"quoted"`, "a,b"},
+	},
+}
+
+func TestContentCRUD(t *testing.T) {
+	for _, b := range backends {
+		for _, r := range resources {
+			t.Run(b.name+"/"+r.name, func(t *testing.T) {
+				s := newTestServer(t, b.open)
+
+				status, created := s.do(http.MethodPost, r.path, r.create)
+				if status != http.StatusCreated {
+					t.Fatalf("create: status %d, body %v", status, created)
+				}
+				assertArray(t, "create", created[r.arrayField], r.created)
+				id := strconv.Itoa(int(created["id"].(float64)))
+
+				status, got := s.do(http.MethodGet, r.path+"/"+id, "")
+				if status != http.StatusOK {
+					t.Fatalf("get: status %d, body %v", status, got)
+				}
+				assertArray(t, "get", got[r.arrayField], r.created)
+				if got["title"] != created["title"] {
+					t.Errorf("get: title %v, want %v", got["title"], created["title"])
+				}
+
+				status, list := s.do(http.MethodGet, r.path, "")
+				if status != http.StatusOK {
+					t.Fatalf("list: status %d, body %v", status, list)
+				}
+				if data := list["data"].([]interface{}); len(data) != 1 {
+					t.Errorf("list: %d items, want 1", len(data))
+				}
+
+				status, updated := s.doWith(http.MethodPut, r.path+"/"+id, r.update, ifMatch(created))
+				if status != http.StatusOK {
+					t.Fatalf("update: status %d, body %v", status, updated)
+				}
+				assertArray(t, "update", updated[r.arrayField], r.updated)
+				_, got = s.do(http.MethodGet, r.path+"/"+id, "")
+				assertArray(t, "get after update", got[r.arrayField], r.updated)
+
+				if status, body := s.doWith(http.MethodDelete, r.path+"/"+id, "", ifMatch(updated)); status != http.StatusNoContent {
+					t.Fatalf("delete: status %d, body %v", status, body)
+				}
+				if status, _ := s.do(http.MethodGet, r.path+"/"+id, ""); status != http.StatusNotFound {
+					t.Errorf("get after delete: status %d, want 404", status)
+				}
+				if status, _ := s.doWith(http.MethodDelete, r.path+"/"+id, "", ifMatch(updated)); status != http.StatusNotFound {
+					t.Errorf("second delete: status %d, want 404", status)
+				}
+			})
+		}
+	}
+}
+
+func TestContentErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string // %s is replaced by the resource path
+		body     string // "valid" is replaced by the resource's create body
+		noAuth   bool
+		wantCode int
+		wantType string
+	}{
+		{"get missing", http.MethodGet, "%s/999", "", false, http.StatusNotFound, "not_found"},
+		{"update missing", http.MethodPut, "%s/999", "valid", false, http.StatusNotFound, "not_found"},
+		{"delete missing", http.MethodDelete, "%s/999", "", false, http.StatusNotFound, "not_found"},
+		{"invalid id", http.MethodGet, "%s/abc", "", false, http.StatusBadRequest, "bad_request"},
+		{"zero id", http.MethodDelete, "%s/0", "", false, http.StatusBadRequest, "bad_request"},
+		{"bad json on create", http.MethodPost, "%s", `{"title":`, false, http.StatusBadRequest, "bad_request"},
+		{"bad json on update", http.MethodPut, "%s/1", `[1,2`, false, http.StatusBadRequest, "bad_request"},
+		{"empty body", http.MethodPost, "%s", `{}`, false, http.StatusUnprocessableEntity, "validation_failed"},
+		{"invalid update", http.MethodPut, "%s/1", `{}`, false, http.StatusUnprocessableEntity, "validation_failed"},
+		{"array of wrong type", http.MethodPost, "%s", `{"title":"x","description":"x","technologies":"Go","skills":"Go"}`, false, http.StatusBadRequest, "bad_request"},
+		{"create without credentials", http.MethodPost, "%s", `{}`, true, http.StatusUnauthorized, "unauthorized"},
+		{"delete without credentials", http.MethodDelete, "%s/1", "", true, http.StatusUnauthorized, "unauthorized"},
+		{"bad page", http.MethodGet, "%s?page=0", "", false, http.StatusBadRequest, "bad_request"},
+		{"unknown sort", http.MethodGet, "%s?sort=nope", "", false, http.StatusBadRequest, "bad_request"},
+	}
+
+	for _, b := range backends {
+		for _, r := range resources {
+			s := newTestServer(t, b.open)
+			// Give the update cases an existing row to hit
+			if status, body := s.do(http.MethodPost, r.path, r.create); status != http.StatusCreated {
+				t.Fatalf("%s: create: status %d, body %v", r.name, status, body)
+			}
+
+			for _, tt := range tests {
+				t.Run(b.name+"/"+r.name+"/"+tt.name, func(t *testing.T) {
+					s.t = t
+					apiKey := s.apiKey
+					if tt.noAuth {
+						apiKey = ""
+					}
+					reqBody := tt.body
+					if reqBody == "valid" {
+						reqBody = r.create
+						if tt.method == http.MethodPut {
+							reqBody = r.update
+						}
+					}
+					status, body := s.send(tt.method, strings.Replace(tt.path, "%s", r.path, 1), reqBody, apiKey)
+					if status != tt.wantCode {
+						t.Fatalf("status %d, want %d (body %v)", status, tt.wantCode, body)
+					}
+					if body["code"] != tt.wantType {
+						t.Errorf("code %v, want %s", body["code"], tt.wantType)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestListingFilters(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			for _, body := range []string{
+				`{"title":"Alpha","description":"a","technologies":["Go","C, C++"],"link":""}`,
+				`{"title":"Beta","description":"b","technologies":["Go"],"link":""}`,
+				`{"title":"Gamma","description":"c","technologies":["C"],"link":""}`,
+			} {
+				if status, resp := s.do(http.MethodPost, "/projects", body); status != http.StatusCreated {
+					t.Fatalf("create: status %d, body %v", status, resp)
+				}
+			}
+
+			tests := []struct {
+				query string
+				want  []string
+			}{
+				{"technology=Go", []string{"Alpha", "Beta"}},
+				{"technology=C", []string{"Gamma"}},
+				{"technology=C,%20C%2B%2B", []string{"Alpha"}},
+				{"title=amm", []string{"Gamma"}},
+				{"sort=-title", []string{"Gamma", "Beta", "Alpha"}},
+				{"sort=title&per_page=2&page=2", []string{"Gamma"}},
+			}
+			for _, tt := range tests {
+				status, body := s.do(http.MethodGet, "/projects?"+tt.query, "")
+				if status != http.StatusOK {
+					t.Fatalf("%s: status %d, body %v", tt.query, status, body)
+				}
+				var titles []string
+				for _, item := range body["data"].([]interface{}) {
+					titles = append(titles, item.(map[string]interface{})["title"].(string))
+				}
+				if strings.Join(titles, ",") != strings.Join(tt.want, ",") {
+					t.Errorf("%s: got %v, want %v", tt.query, titles, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestListingCursorPagination(t *testing.T) {
+	// Titles and positions repeat, so every sort below needs the id
+	// tie-breaker to page without skipping or repeating items.
+	projects := []struct {
+		title    string
+		position int
+	}{
+		{"Delta", 2}, {"Alpha", 0}, {"Charlie", 1}, {"Alpha", 1}, {"Bravo", 0}, {"Charlie", 2}, {"Alpha", 0},
+	}
+
+	tests := []struct {
+		sort string
+		want []int // indexes into projects
+	}{
+		{"", []int{1, 4, 6, 2, 3, 0, 5}},
+		{"title", []int{1, 3, 6, 4, 2, 5, 0}},
+		{"-title", []int{0, 2, 5, 4, 1, 3, 6}},
+		{"-title,-id", []int{0, 5, 2, 4, 6, 3, 1}},
+		{"-position,title", []int{5, 0, 3, 2, 1, 6, 4}},
+	}
+
+	next := regexp.MustCompile(`<([^>]+)>; rel="next"`)
+
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			index := map[float64]int{}
+			for i, p := range projects {
+				body := fmt.Sprintf(`{"title":%q,"description":"d","technologies":["Go"],"link":"","position":%d}`, p.title, p.position)
+				status, created := s.do(http.MethodPost, "/projects", body)
+				if status != http.StatusCreated {
+					t.Fatalf("create: status %d, body %v", status, created)
+				}
+				index[created["id"].(float64)] = i
+			}
+
+			get := func(path string) (http.Header, map[string]interface{}) {
+				t.Helper()
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				resp, err := s.app.Test(req, -1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				var body map[string]interface{}
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("GET %s: status %d, body %v", path, resp.StatusCode, body)
+				}
+				return resp.Header, body
+			}
+
+			for _, tt := range tests {
+				// The first page is in page mode; its cursor switches to
+				// cursor mode, whose Link header leads to the end.
+				path := "/projects?per_page=2&sort=" + url.QueryEscape(tt.sort)
+				_, first := get(path)
+				cursor, _ := first["meta"].(map[string]interface{})["next_cursor"].(string)
+				if cursor == "" {
+					t.Fatalf("sort %q: first page has no next_cursor", tt.sort)
+				}
+
+				var got []int
+				for _, item := range first["data"].([]interface{}) {
+					got = append(got, index[item.(map[string]interface{})["id"].(float64)])
+				}
+				path += "&after=" + url.QueryEscape(cursor)
+				for pages := 0; path != ""; pages++ {
+					if pages > len(projects) {
+						t.Fatalf("sort %q: next links do not end, got %v", tt.sort, got)
+					}
+					header, body := get(path)
+					for _, item := range body["data"].([]interface{}) {
+						got = append(got, index[item.(map[string]interface{})["id"].(float64)])
+					}
+
+					path = ""
+					if m := next.FindStringSubmatch(header.Get("Link")); m != nil {
+						u, err := url.Parse(m[1])
+						if err != nil {
+							t.Fatal(err)
+						}
+						path = u.RequestURI()
+					}
+					cursor, _ := body["meta"].(map[string]interface{})["next_cursor"].(string)
+					if (cursor == "") != (path == "") {
+						t.Fatalf("sort %q: next_cursor %q but Link %q", tt.sort, cursor, header.Get("Link"))
+					}
+				}
+
+				if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+					t.Errorf("sort %q: got %v, want %v", tt.sort, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestListingFilterWildcards(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			for _, title := range []string{"100% Go", "1000 Go", "a_b", "axb", `back\\slash`} {
+				if status, resp := s.do(http.MethodPost, "/projects", `{"title":"`+title+`","description":"d","technologies":["Go"]}`); status != http.StatusCreated {
+					t.Fatalf("create: status %d, body %v", status, resp)
+				}
+			}
+
+			// %, _ and \ in a filter match themselves, not any characters
+			tests := []struct {
+				query string
+				want  string
+			}{
+				{"title=100%25", "100% Go"},
+				{"title=a_b", "a_b"},
+				{"title=k%5Cs", `back\slash`},
+			}
+			for _, tt := range tests {
+				status, body := s.do(http.MethodGet, "/projects?"+tt.query, "")
+				if status != http.StatusOK {
+					t.Fatalf("%s: status %d, body %v", tt.query, status, body)
+				}
+				data := body["data"].([]interface{})
+				if len(data) != 1 || data[0].(map[string]interface{})["title"] != tt.want {
+					t.Errorf("%s: got %v, want only %q", tt.query, data, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func assertArray(t *testing.T, step string, got interface{}, want []string) {
+	t.Helper()
+	items, ok := got.([]interface{})
+	if !ok {
+		t.Fatalf("%s: array is %T, want a JSON array", step, got)
+	}
+	if len(items) != len(want) {
+		t.Fatalf("%s: got %v, want %q", step, items, want)
+	}
+	for i := range want {
+		if items[i] != want[i] {
+			t.Errorf("%s: element %d is %q, want %q", step, i, items[i], want[i])
+		}
+	}
+}
+
+// ifMatch returns an If-Match header naming the version of a decoded
+// record.
+func ifMatch(record map[string]interface{}) map[string]string {
+	return map[string]string{"If-Match": `"` + strconv.Itoa(int(record["version"].(float64))) + `"`}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// Run serves app on cfg.Addr() until ctx is cancelled, then stops
+// accepting connections and gives in-flight requests cfg.ShutdownTimeout
+// to finish. It returns the listen error, or the shutdown error when the
+// deadline cut requests off.
+func Run(ctx context.Context, app *fiber.App, cfg Config) error {
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.Listen(cfg.Addr())
+	}()
+	slog.Info("Server listening", "addr", cfg.Addr())
+
+	select {
+	case err := <-listenErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down, draining in-flight requests", "timeout", cfg.ShutdownTimeout.String())
+	err := app.ShutdownWithTimeout(cfg.ShutdownTimeout)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errors.New("shutdown deadline passed before in-flight requests finished")
+	}
+	return err
+}
+
+// inflight tracks the requests being served, so a shutdown that hits its
+// deadline can log which requests it cut off.
+type inflight struct {
+	mu     sync.Mutex
+	nextID uint64
+	active map[uint64]string
+}
+
+func newInflight() *inflight {
+	return &inflight{active: make(map[uint64]string)}
+}
+
+// track is a middleware recording the request for as long as it runs.
+func (f *inflight) track(c *fiber.Ctx) error {
+	desc := c.Method() + " " + utils.CopyString(c.OriginalURL())
+	if id, ok := c.Locals("requestid").(string); ok {
+		desc += " (" + id + ")"
+	}
+
+	f.mu.Lock()
+	f.nextID++
+	id := f.nextID
+	f.active[id] = desc
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		delete(f.active, id)
+		f.mu.Unlock()
+	}()
+	return c.Next()
+}
+
+// list describes the requests still running, oldest first.
+func (f *inflight) list() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids := make([]uint64, 0, len(f.active))
+	for id := range f.active {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	descs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		descs = append(descs, f.active[id])
+	}
+	return descs
+}
+
+// logCutOff runs once the server has shut down; any request still tracked
+// at that point was cut off by the shutdown deadline.
+func (f *inflight) logCutOff() error {
+	if cut := f.list(); len(cut) > 0 {
+		slog.Warn("Shutdown cut off in-flight requests", "count", len(cut), "requests", cut)
+	}
+	return nil
+}
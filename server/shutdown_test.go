@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"wannn-site-rebuild-api/repository"
+)
+
+func TestRunDrainsInFlightRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		work      time.Duration
+		timeout   time.Duration
+		wantErr   bool
+		wantReply bool
+	}{
+		{"request finishes within the deadline", 200 * time.Millisecond, 2 * time.Second, false, true},
+		{"request outlives the deadline", 2 * time.Second, 200 * time.Millisecond, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Port = freePort(t)
+			cfg.ShutdownTimeout = tt.timeout
+			app := New(cfg, Deps{Repos: repository.NewMemory()})
+			app.Get("/slow", func(c *fiber.Ctx) error {
+				time.Sleep(tt.work)
+				return c.SendString("done")
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			runErr := make(chan error, 1)
+			go func() { runErr <- Run(ctx, app, cfg) }()
+			waitForListener(t, cfg.Addr())
+
+			replied := make(chan bool, 1)
+			go func() {
+				resp, err := http.Get("http://127.0.0.1" + cfg.Addr() + "/slow")
+				if err == nil {
+					resp.Body.Close()
+				}
+				replied <- err == nil && resp.StatusCode == http.StatusOK
+			}()
+
+			// Signal shutdown while the request is being served
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+
+			select {
+			case err := <-runErr:
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Run returned %v, want error: %v", err, tt.wantErr)
+				}
+			case <-time.After(tt.timeout + time.Second):
+				t.Fatal("Run did not return after the shutdown deadline")
+			}
+
+			if tt.wantReply {
+				if !<-replied {
+					t.Error("in-flight request was not answered")
+				}
+			}
+
+			// No new connections are accepted once shut down
+			if conn, err := net.DialTimeout("tcp", "127.0.0.1"+cfg.Addr(), 100*time.Millisecond); err == nil {
+				conn.Close()
+				t.Error("server still accepts connections after shutdown")
+			}
+		})
+	}
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func waitForListener(t *testing.T, addr string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if conn, err := net.Dial("tcp", "127.0.0.1"+addr); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("server did not start listening on " + addr)
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"wannn-site-rebuild-api/apperr"
+)
+
+// recordSpans installs a tracer provider that records every span for the
+// rest of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return recorder
+}
+
+func TestTracing(t *testing.T) {
+	recorder := recordSpans(t)
+
+	s := newTestServer(t, backends[1].open) // sqlite, so queries go through GORM
+	s.do("POST", "/projects", `{"title":"Traced","description":"d","technologies":["Go"]}`)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("GET", "/projects/1", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	if _, err := s.app.Test(req, -1); err != nil {
+		t.Fatal(err)
+	}
+
+	var server sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "GET /projects/:id" {
+			server = span
+		}
+	}
+	if server == nil {
+		t.Fatal("no server span for GET /projects/:id")
+	}
+	if got := server.SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("server span trace %s, want it continued from traceparent %s", got, traceID)
+	}
+	if got := server.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("server span parent %s, want the caller's span", got)
+	}
+	attrs := map[string]string{}
+	for _, kv := range server.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["http.route"] != "/projects/:id" || attrs["http.response.status_code"] != "200" {
+		t.Errorf("server span attributes %v", attrs)
+	}
+
+	var queries int
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() != server.SpanContext().SpanID() {
+			continue
+		}
+		queries++
+		if !strings.HasPrefix(span.Name(), "db.select projects") {
+			t.Errorf("child span %q, want a projects select", span.Name())
+		}
+		for _, kv := range span.Attributes() {
+			if kv.Key == "db.query.text" && !strings.Contains(kv.Value.AsString(), "projects") {
+				t.Errorf("db.query.text %q", kv.Value.AsString())
+			}
+		}
+	}
+	if queries == 0 {
+		t.Error("no query spans under the request span")
+	}
+}
+
+func TestTracingRecordsHandlerErrors(t *testing.T) {
+	recorder := recordSpans(t)
+	s := newTestServer(t, backends[0].open)
+	s.app.Get("/broken", func(c *fiber.Ctx) error {
+		return apperr.Internal("An unexpected error occurred", errors.New("disk on fire"))
+	})
+
+	tests := []struct {
+		path       string
+		wantName   string
+		wantStatus codes.Code
+		wantError  bool
+	}{
+		{"/broken", "GET /broken", codes.Error, true},
+		{"/projects/999", "GET /projects/:id", codes.Unset, true},
+		{"/nowhere", "GET", codes.Unset, true},
+		{"/projects", "GET /projects/", codes.Unset, false},
+	}
+	for _, tt := range tests {
+		before := len(recorder.Ended())
+		if _, err := s.app.Test(httptest.NewRequest("GET", tt.path, nil), -1); err != nil {
+			t.Fatal(err)
+		}
+		ended := recorder.Ended()
+		if len(ended) == before {
+			t.Fatalf("%s: no spans", tt.path)
+		}
+		server := ended[len(ended)-1]
+		if server.Name() != tt.wantName {
+			t.Errorf("%s: span %q, want %q", tt.path, server.Name(), tt.wantName)
+		}
+		if server.Status().Code != tt.wantStatus {
+			t.Errorf("%s: span status %v, want %v", tt.path, server.Status().Code, tt.wantStatus)
+		}
+		var recorded bool
+		for _, event := range server.Events() {
+			recorded = recorded || event.Name == "exception"
+		}
+		if recorded != tt.wantError {
+			t.Errorf("%s: error recorded %v, want %v", tt.path, recorded, tt.wantError)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrash(t *testing.T) {
+	for _, b := range backends {
+		for _, r := range resources {
+			t.Run(b.name+"/"+r.name, func(t *testing.T) {
+				s := newTestServer(t, b.open)
+				_, created := s.do(http.MethodPost, r.path, r.create)
+				id := strconv.Itoa(int(created["id"].(float64)))
+				path := r.path + "/" + id
+
+				trash := func() []interface{} {
+					t.Helper()
+					status, body := s.do(http.MethodGet, r.path+"/trash", "")
+					if status != http.StatusOK {
+						t.Fatalf("trash: status %d, body %v", status, body)
+					}
+					return body["data"].([]interface{})
+				}
+
+				if got := trash(); len(got) != 0 {
+					t.Fatalf("trash holds %d items before any delete", len(got))
+				}
+				if status, _ := s.doWith(http.MethodDelete, path, "", ifMatch(created)); status != http.StatusNoContent {
+					t.Fatalf("delete: status %d", status)
+				}
+				if status, _ := s.do(http.MethodGet, path, ""); status != http.StatusNotFound {
+					t.Errorf("get after delete: status %d, want 404", status)
+				}
+
+				items := trash()
+				if len(items) != 1 {
+					t.Fatalf("trash holds %d items, want 1", len(items))
+				}
+				trashed := items[0].(map[string]interface{})
+				if trashed["id"] != created["id"] || trashed["deleted_at"] == nil {
+					t.Errorf("trash item %v, want id %v with deleted_at", trashed, created["id"])
+				}
+				if status, _ := s.send(http.MethodGet, r.path+"/trash", "", ""); status != http.StatusUnauthorized {
+					t.Errorf("anonymous trash: status %d, want 401", status)
+				}
+
+				status, restored := s.do(http.MethodPost, path+"/restore", "")
+				if status != http.StatusOK {
+					t.Fatalf("restore: status %d, body %v", status, restored)
+				}
+				if restored["deleted_at"] != nil || restored["version"] != float64(2) {
+					t.Errorf("restored %v, want it live at version 2", restored)
+				}
+				assertArray(t, "restored", restored[r.arrayField], r.created)
+				if status, _ := s.do(http.MethodGet, path, ""); status != http.StatusOK {
+					t.Errorf("get after restore: status %d, want 200", status)
+				}
+				if status, _ := s.do(http.MethodPost, path+"/restore", ""); status != http.StatusNotFound {
+					t.Errorf("restore of a live record: status %d, want 404", status)
+				}
+
+				// Hard deletes need the current version too, and work on
+				// live records as well as the trash
+				if status, _ := s.doWith(http.MethodDelete, path+"?hard=true", "", ifMatch(created)); status != http.StatusPreconditionFailed {
+					t.Errorf("hard delete with a stale ETag: status %d, want 412", status)
+				}
+				if status, _ := s.doWith(http.MethodDelete, path+"?hard=maybe", "", ifMatch(restored)); status != http.StatusBadRequest {
+					t.Errorf("hard delete with a bad flag: status %d, want 400", status)
+				}
+				if status, _ := s.doWith(http.MethodDelete, path+"?hard=true", "", ifMatch(restored)); status != http.StatusNoContent {
+					t.Fatalf("hard delete: status %d", status)
+				}
+				if got := trash(); len(got) != 0 {
+					t.Errorf("trash holds %d items after a hard delete, want 0", len(got))
+				}
+				if status, _ := s.do(http.MethodPost, path+"/restore", ""); status != http.StatusNotFound {
+					t.Errorf("restore after a hard delete: status %d, want 404", status)
+				}
+			})
+		}
+	}
+}
+
+func TestRestoreGoesLast(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			create := func(title string) map[string]interface{} {
+				t.Helper()
+				status, body := s.do(http.MethodPost, "/projects", `{"title":"`+title+`","description":"d","technologies":["Go"]}`)
+				if status != http.StatusCreated {
+					t.Fatalf("create %s: status %d, body %v", title, status, body)
+				}
+				return body
+			}
+			create("A")
+			create("B")
+			c := create("C")
+			path := "/projects/" + strconv.Itoa(int(c["id"].(float64)))
+
+			// D takes the position C had before it went to the trash
+			s.doWith(http.MethodDelete, path, "", ifMatch(c))
+			if d := create("D"); d["position"] != c["position"] {
+				t.Fatalf("D at position %v, want C's old %v", d["position"], c["position"])
+			}
+
+			status, restored := s.do(http.MethodPost, path+"/restore", "")
+			if status != http.StatusOK {
+				t.Fatalf("restore: status %d, body %v", status, restored)
+			}
+			if restored["position"] != float64(3) {
+				t.Errorf("restored at position %v, want 3", restored["position"])
+			}
+
+			_, list := s.do(http.MethodGet, "/projects", "")
+			var titles []string
+			positions := map[float64]bool{}
+			for _, item := range list["data"].([]interface{}) {
+				p := item.(map[string]interface{})
+				titles = append(titles, p["title"].(string))
+				positions[p["position"].(float64)] = true
+			}
+			if strings.Join(titles, ",") != "A,B,D,C" || len(positions) != len(titles) {
+				t.Errorf("list %v with %d distinct positions, want A,B,D,C with 4", titles, len(positions))
+			}
+		})
+	}
+}
+
+func TestHardDeleteFromTrash(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			_, created := s.do(http.MethodPost, "/projects", `{"title":"Portfolio","description":"My site","technologies":["Go"]}`)
+			path := "/projects/" + strconv.Itoa(int(created["id"].(float64)))
+
+			s.doWith(http.MethodDelete, path, "", ifMatch(created))
+			if status, _ := s.doWith(http.MethodDelete, path, "", ifMatch(created)); status != http.StatusNotFound {
+				t.Errorf("soft delete of a trashed record: status %d, want 404", status)
+			}
+			if status, _ := s.doWith(http.MethodDelete, path+"?hard=true", "", ifMatch(created)); status != http.StatusNoContent {
+				t.Fatalf("hard delete from the trash: status %d", status)
+			}
+			if _, body := s.do(http.MethodGet, "/projects/trash", ""); len(body["data"].([]interface{})) != 0 {
+				t.Errorf("trash %v, want it empty", body["data"])
+			}
+		})
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := newTestServer(t, b.open)
+			for _, title := range []string{"Old", "Recent"} {
+				_, created := s.do(http.MethodPost, "/projects", `{"title":"`+title+`","description":"d","technologies":["Go"]}`)
+				s.doWith(http.MethodDelete, "/projects/"+strconv.Itoa(int(created["id"].(float64))), "", ifMatch(created))
+				time.Sleep(10 * time.Millisecond)
+			}
+			s.do(http.MethodPost, "/projects", `{"title":"Live","description":"d","technologies":["Go"]}`)
+
+			_, body := s.do(http.MethodGet, "/projects/trash?sort=title", "")
+			recent := body["data"].([]interface{})[1].(map[string]interface{})
+			cutoff, err := time.Parse(time.RFC3339Nano, recent["deleted_at"].(string))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Only content deleted before the cutoff goes; live content stays
+			purgeExpired(context.Background(), s.repos, cutoff)
+
+			_, body = s.do(http.MethodGet, "/projects/trash", "")
+			trashed := body["data"].([]interface{})
+			if len(trashed) != 1 || trashed[0].(map[string]interface{})["title"] != "Recent" {
+				t.Errorf("trash after purge %v, want only Recent", trashed)
+			}
+			if _, body := s.do(http.MethodGet, "/projects", ""); len(body["data"].([]interface{})) != 1 {
+				t.Errorf("live projects %v, want Live untouched", body["data"])
+			}
+		})
+	}
+}
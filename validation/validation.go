@@ -0,0 +1,195 @@
+// Package validation checks request structs against rules declared in
+// `validate` struct tags, e.g.
+//
+//	Title string   `json:"title" validate:"required,max=255"`
+//	Tags  []string `json:"tags" validate:"required,max=20,unique,itemmax=50"`
+//
+// Supported rules:
+//
+//	required      strings must not be blank, slices must not be empty
+//	max=N         maximum string length in characters, slice length or number
+//	min=N         minimum string length in characters, slice length or number
+//	url           absolute http(s) URL; empty strings are left to required
+//	email         plausible email address; empty strings are left to required
+//	unique        no duplicate entries in a string slice
+//	itemmax=N     maximum length of each entry in a string slice
+//	itemrequired  no blank entries in a string slice
+//
+// Pointer fields are checked through; a nil pointer fails required and
+// passes every other rule.
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Errors maps a JSON field name to the first rule it failed.
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		parts = append(parts, field+": "+e[field])
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// Validate checks every tagged field of the struct v points to (or is) and
+// returns nil when all rules pass.
+func Validate(v interface{}) Errors {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: expected struct, got %s", rv.Kind()))
+	}
+
+	errs := Errors{}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		name := jsonName(field)
+		value := rv.Field(i)
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				if strings.Contains(","+tag+",", ",required,") {
+					errs[name] = "is required"
+				}
+				continue
+			}
+			value = value.Elem()
+		}
+		for _, rule := range strings.Split(tag, ",") {
+			if msg := check(value, rule); msg != "" {
+				errs[name] = msg
+				break
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func check(v reflect.Value, rule string) string {
+	name, arg, _ := strings.Cut(rule, "=")
+	switch name {
+	case "required":
+		return checkRequired(v)
+	case "max", "min":
+		n := mustAtoi(rule, arg)
+		size, unit := length(v)
+		if name == "max" && size > n {
+			return strings.TrimSpace(fmt.Sprintf("must be at most %d %s", n, unit))
+		}
+		if name == "min" && size < n {
+			return strings.TrimSpace(fmt.Sprintf("must be at least %d %s", n, unit))
+		}
+	case "url":
+		if s := v.String(); s != "" && !isHTTPURL(s) {
+			return "must be a valid http or https URL"
+		}
+	case "email":
+		if s := v.String(); s != "" && !isEmail(s) {
+			return "must be a valid email address"
+		}
+	case "unique":
+		seen := make(map[string]bool, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i).String()
+			if seen[item] {
+				return fmt.Sprintf("must not contain duplicates (%q appears more than once)", item)
+			}
+			seen[item] = true
+		}
+	case "itemmax":
+		n := mustAtoi(rule, arg)
+		for i := 0; i < v.Len(); i++ {
+			if utf8.RuneCountInString(v.Index(i).String()) > n {
+				return fmt.Sprintf("entry %d must be at most %d characters", i, n)
+			}
+		}
+	case "itemrequired":
+		for i := 0; i < v.Len(); i++ {
+			if strings.TrimSpace(v.Index(i).String()) == "" {
+				return fmt.Sprintf("entry %d must not be blank", i)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("validation: unknown rule %q", rule))
+	}
+	return ""
+}
+
+func checkRequired(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		if strings.TrimSpace(v.String()) == "" {
+			return "is required"
+		}
+	case reflect.Slice, reflect.Map:
+		if v.Len() == 0 {
+			return "must not be empty"
+		}
+	default:
+		if v.IsZero() {
+			return "is required"
+		}
+	}
+	return ""
+}
+
+// length returns the size min and max compare, and its unit. Numbers are
+// compared by value and have no unit.
+func length(v reflect.Value) (int, string) {
+	switch v.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(v.String()), "characters"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int()), ""
+	}
+	return v.Len(), "items"
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.ParseRequestURI(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func mustAtoi(rule, arg string) int {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		panic(fmt.Sprintf("validation: rule %q needs a numeric argument", rule))
+	}
+	return n
+}
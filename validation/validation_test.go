@@ -0,0 +1,114 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	type request struct {
+		Title    string   `json:"title" validate:"required,max=5"`
+		Summary  string   `json:"summary" validate:"min=2"`
+		Link     string   `json:"link" validate:"url"`
+		Email    string   `json:"email" validate:"email"`
+		Tags     []string `json:"tags" validate:"required,max=2,unique,itemrequired,itemmax=3"`
+		Position *int     `json:"position" validate:"min=0,max=9"`
+		Owner    *string  `json:"owner" validate:"required"`
+		Count    int      `json:"count" validate:"max=3"`
+		Untagged string   `json:"untagged"`
+	}
+
+	intPtr := func(n int) *int { return &n }
+	owner := "me"
+	valid := func() request {
+		return request{Title: "Go", Summary: "ok", Tags: []string{"a"}, Owner: &owner}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*request)
+		field  string
+		want   string
+	}{
+		{"valid", func(*request) {}, "", ""},
+		{"blank string", func(r *request) { r.Title = "  " }, "title", "is required"},
+		{"empty slice", func(r *request) { r.Tags = nil }, "tags", "must not be empty"},
+		{"string too long", func(r *request) { r.Title = "Golang" }, "title", "must be at most 5 characters"},
+		{"max counts characters", func(r *request) { r.Title = "ÜÜÜÜÜ" }, "", ""},
+		{"string too short", func(r *request) { r.Summary = "x" }, "summary", "must be at least 2 characters"},
+		{"slice too long", func(r *request) { r.Tags = []string{"a", "b", "c"} }, "tags", "must be at most 2 items"},
+		{"number too large", func(r *request) { r.Count = 4 }, "count", "must be at most 3"},
+		{"empty url", func(r *request) { r.Link = "" }, "", ""},
+		{"http url", func(r *request) { r.Link = "https://example.com/a" }, "", ""},
+		{"relative url", func(r *request) { r.Link = "/path" }, "link", "must be a valid http or https URL"},
+		{"other scheme", func(r *request) { r.Link = "ftp://example.com" }, "link", "must be a valid http or https URL"},
+		{"email", func(r *request) { r.Email = "me@example.com" }, "", ""},
+		{"email with a name", func(r *request) { r.Email = "Me <me@example.com>" }, "email", "must be a valid email address"},
+		{"duplicates", func(r *request) { r.Tags = []string{"a", "a"} }, "tags", `must not contain duplicates ("a" appears more than once)`},
+		{"blank item", func(r *request) { r.Tags = []string{"a", " "} }, "tags", "entry 1 must not be blank"},
+		{"long item", func(r *request) { r.Tags = []string{"abcd"} }, "tags", "entry 0 must be at most 3 characters"},
+		{"nil optional pointer", func(r *request) { r.Position = nil }, "", ""},
+		{"pointer in range", func(r *request) { r.Position = intPtr(0) }, "", ""},
+		{"pointer below min", func(r *request) { r.Position = intPtr(-1) }, "position", "must be at least 0"},
+		{"pointer above max", func(r *request) { r.Position = intPtr(10) }, "position", "must be at most 9"},
+		{"nil required pointer", func(r *request) { r.Owner = nil }, "owner", "is required"},
+		{"blank required pointer", func(r *request) { blank := ""; r.Owner = &blank }, "owner", "is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.modify(&r)
+			errs := Validate(r)
+			if tt.field == "" {
+				if errs != nil {
+					t.Fatalf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[tt.field] != tt.want {
+				t.Errorf("errors %v, want only %s: %s", errs, tt.field, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryField(t *testing.T) {
+	type request struct {
+		A string `json:"a" validate:"required"`
+		B string `json:"b" validate:"required,max=1"`
+		C string `validate:"required"`
+	}
+	errs := Validate(&request{B: "long"})
+	if len(errs) != 3 || errs["a"] != "is required" || errs["b"] != "must be at most 1 characters" || errs["C"] != "is required" {
+		t.Errorf("errors %v, want a, b and C (named after the Go field without a json tag)", errs)
+	}
+	if msg := errs.Error(); !strings.HasPrefix(msg, "validation failed: C: is required; a: ") {
+		t.Errorf("Error() = %q, want fields in sorted order", msg)
+	}
+}
+
+func TestValidatePanicsOnBadTags(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"unknown rule", struct {
+			A string `validate:"oneof=a b"`
+		}{}},
+		{"non-numeric argument", struct {
+			A string `validate:"max=many"`
+		}{}},
+		{"not a struct", "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Validate did not panic")
+				}
+			}()
+			Validate(tt.v)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Package version holds build metadata injected with -ldflags:
+//
+//	go build -ldflags "-X wannn-site-rebuild-api/version.Commit=$(git rev-parse HEAD) \
+//	  -X wannn-site-rebuild-api/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without ldflags the commit falls back to the VCS stamp Go records in the
+// binary when available.
+package version
+
+import "runtime/debug"
+
+// Set at build time with -ldflags "-X wannn-site-rebuild-api/version.<Name>=...".
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info describes the running build.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+// Get returns the build metadata, falling back to the VCS revision stamped
+// by go build when no commit was injected.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, BuildTime: BuildTime}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.GoVersion = bi.GoVersion
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}